@@ -0,0 +1,189 @@
+// Package apitokens manages personal access tokens, which let scripts call
+// the API with an "Authorization: Bearer" header instead of a session cookie.
+// Only a hash of each token is stored.
+package apitokens
+
+import (
+	db "PamQ/database"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const tokenPrefix = "pamq_"
+
+const (
+	QuizRead     = "quiz:read"
+	QuizWrite    = "quiz:write"
+	QuizSubmit   = "quiz:submit"
+	ResultsRead  = "results:read"
+	ResultsGrade = "results:grade"
+)
+
+var Scopes = []string{QuizRead, QuizWrite, QuizSubmit, ResultsRead, ResultsGrade}
+
+var ErrNotFound = errors.New("apitokens: token not found")
+
+type Token struct {
+	ID          int        `json:"id"`
+	Username    string     `json:"-"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	LastUsed    *time.Time `json:"last_used"`
+	DateCreated time.Time  `json:"date_created"`
+}
+
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *Token) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Hash returns what is stored of secret.
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Expiry returns when a token created at now for days expires, or the zero
+// time for days 0.
+func Expiry(now time.Time, days int) time.Time {
+	if days <= 0 {
+		return time.Time{}
+	}
+	return now.AddDate(0, 0, days)
+}
+
+// New generates a token with its secret without storing it. A zero
+// expiresAt makes a token that doesn't expire.
+func New(username, name string, scopes []string, expiresAt time.Time) (string, *Token, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, err
+	}
+	secret := tokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	t := &Token{
+		Username:    username,
+		Name:        name,
+		Prefix:      secret[:len(tokenPrefix)+6],
+		Scopes:      scopes,
+		DateCreated: time.Now(),
+	}
+	if !expiresAt.IsZero() {
+		t.ExpiresAt = &expiresAt
+	}
+	return secret, t, nil
+}
+
+// Create stores a new token and returns it with its secret, which can't be
+// retrieved again.
+func Create(username, name string, scopes []string, expiresAt time.Time) (string, *Token, error) {
+	secret, t, err := New(username, name, scopes, expiresAt)
+	if err != nil {
+		return "", nil, err
+	}
+
+	db := db.DB
+	row := db.QueryRow(`INSERT INTO api_token (username, name, token_hash, prefix, scopes, expires_at, date_created) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		t.Username, t.Name, Hash(secret), t.Prefix, pq.Array(t.Scopes), t.ExpiresAt, t.DateCreated)
+	if err := row.Scan(&t.ID); err != nil {
+		return "", nil, err
+	}
+	return secret, t, nil
+}
+
+const tokenColumns = `id, username, name, prefix, scopes, expires_at, last_used, date_created`
+
+func scanToken(row interface{ Scan(...interface{}) error }) (*Token, error) {
+	var t Token
+	var expiresAt, lastUsed pq.NullTime
+	err := row.Scan(&t.ID, &t.Username, &t.Name, &t.Prefix, pq.Array(&t.Scopes), &expiresAt, &lastUsed, &t.DateCreated)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		t.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsed.Valid {
+		t.LastUsed = &lastUsed.Time
+	}
+	return &t, nil
+}
+
+// Resolve returns the unexpired token with the given secret.
+func Resolve(secret string) (*Token, error) {
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return nil, ErrNotFound
+	}
+
+	db := db.DB
+	t, err := scanToken(db.QueryRow(`UPDATE api_token SET last_used=NOW()
+		WHERE token_hash=$1 AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING `+tokenColumns, Hash(secret)))
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func List(username string) ([]Token, error) {
+	db := db.DB
+	rows, err := db.Query(`SELECT `+tokenColumns+` FROM api_token WHERE username=$1 ORDER BY id`, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []Token{}
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+func Revoke(username string, id int) error {
+	db := db.DB
+	res, err := db.Exec(`DELETE FROM api_token WHERE id=$1 AND username=$2`, id, username)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RevokeAll deletes every token of username.
+func RevokeAll(username string) error {
+	db := db.DB
+	_, err := db.Exec(`DELETE FROM api_token WHERE username=$1`, username)
+	return err
+}
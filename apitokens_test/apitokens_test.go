@@ -0,0 +1,87 @@
+package apitokens_test
+
+import (
+	"PamQ/apitokens"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	secret, token, err := apitokens.New("sara", "ci", []string{apitokens.QuizRead}, expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, "pamq_") || len(secret) != len("pamq_")+43 {
+		t.Errorf("Want a pamq_ secret of 32 random bytes, got '%s'", secret)
+	}
+	if !strings.HasPrefix(secret, token.Prefix) || len(token.Prefix) != len("pamq_")+6 {
+		t.Errorf("Want the first characters of the secret as prefix, got '%s'", token.Prefix)
+	}
+	if token.ExpiresAt == nil || !token.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Want expiry %v, got %v", expiresAt, token.ExpiresAt)
+	}
+
+	other, token, err := apitokens.New("sara", "ci", []string{apitokens.QuizRead}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == secret {
+		t.Error("Want a new secret for every token")
+	}
+	if token.ExpiresAt != nil {
+		t.Errorf("Want a token that doesn't expire, got %v", token.ExpiresAt)
+	}
+}
+
+func TestHash(t *testing.T) {
+	secret, _, err := apitokens.New("sara", "ci", nil, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := apitokens.Hash(secret)
+	if hash != apitokens.Hash(secret) {
+		t.Error("Want the same hash for the same secret")
+	}
+	if len(hash) != 64 || strings.Contains(hash, secret[len("pamq_"):]) {
+		t.Errorf("Want a hex SHA-256 not containing the secret, got '%s'", hash)
+	}
+	if hash == apitokens.Hash(secret+"x") {
+		t.Error("Want different hashes for different secrets")
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	now := time.Date(2020, 1, 30, 12, 0, 0, 0, time.UTC)
+	if got := apitokens.Expiry(now, 0); !got.IsZero() {
+		t.Errorf("Want no expiry, got %v", got)
+	}
+	if got, want := apitokens.Expiry(now, 30), time.Date(2020, 2, 29, 12, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Want %v, got %v", want, got)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	for _, scope := range apitokens.Scopes {
+		if !apitokens.ValidScope(scope) {
+			t.Errorf("Want %s to be valid", scope)
+		}
+	}
+	for _, scope := range []string{"", "quiz", "quiz:*", "QUIZ:READ", "admin"} {
+		if apitokens.ValidScope(scope) {
+			t.Errorf("Want %q to be invalid", scope)
+		}
+	}
+
+	token := apitokens.Token{Scopes: []string{apitokens.QuizRead, apitokens.ResultsRead}}
+	if !token.HasScope(apitokens.ResultsRead) || token.HasScope(apitokens.QuizWrite) {
+		t.Errorf("Want only the granted scopes, got %v", token.Scopes)
+	}
+}
+
+func TestResolveRejectsForeignSecrets(t *testing.T) {
+	if _, err := apitokens.Resolve("ghp_0123456789"); err != apitokens.ErrNotFound {
+		t.Errorf("Want %v, got %v", apitokens.ErrNotFound, err)
+	}
+}
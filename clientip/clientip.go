@@ -0,0 +1,107 @@
+// Package clientip finds the address of the client that sent a request.
+//
+// Behind a reverse proxy the connection comes from the proxy, which appends
+// the address it was connected from to X-Forwarded-For. Only entries added
+// by trusted proxies can be believed: anything to their left was sent by the
+// client and may be made up.
+package clientip
+
+import (
+	"PamQ/config"
+	"PamQ/logging"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Resolver reads client addresses from requests.
+type Resolver struct {
+	// TrustProxyHeaders makes the Resolver read X-Forwarded-For.
+	TrustProxyHeaders bool
+	// TrustedProxies are the networks of the proxies in front of the
+	// server. When empty, the proxy connecting to the server is the only
+	// one trusted.
+	TrustedProxies []*net.IPNet
+}
+
+// Default is configured with PAMQ_TRUST_PROXY_HEADERS and
+// PAMQ_TRUSTED_PROXIES, a comma separated list of addresses and CIDR
+// networks.
+var Default = &Resolver{
+	TrustProxyHeaders: config.Bool("TRUST_PROXY_HEADERS", false),
+	TrustedProxies:    ParseNetworks(config.String("TRUSTED_PROXIES", "")),
+}
+
+// ParseNetworks parses a comma separated list of addresses and CIDR
+// networks, skipping invalid entries.
+func ParseNetworks(list string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			logging.Default.Warn("ignoring invalid trusted proxy", "value", entry)
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+func (res *Resolver) trusted(ip net.IP) bool {
+	for _, network := range res.TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// FromRequest returns the client address of r.
+func (res *Resolver) FromRequest(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if !res.TrustProxyHeaders {
+		return remote
+	}
+	if len(res.TrustedProxies) != 0 {
+		if ip := net.ParseIP(remote); ip == nil || !res.trusted(ip) {
+			return remote
+		}
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	// The rightmost entry was added by the proxy connecting to the server.
+	// Going left, every entry added by a trusted proxy names the next hop.
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+		if i == 0 || !res.trusted(ip) {
+			return ip.String()
+		}
+	}
+	return remote
+}
+
+// FromRequest returns the client address of r using Default.
+func FromRequest(r *http.Request) string {
+	return Default.FromRequest(r)
+}
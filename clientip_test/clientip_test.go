@@ -0,0 +1,98 @@
+package clientip_test
+
+import (
+	"PamQ/clientip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFromRequest(t *testing.T) {
+	proxies := clientip.ParseNetworks("10.0.0.0/8, 192.0.2.7, bad")
+
+	tt := []struct {
+		name     string
+		resolver clientip.Resolver
+		remote   string
+		forwards []string
+		want     string
+	}{
+		{
+			name:     "Proxy headers not trusted",
+			remote:   "198.51.100.1:4000",
+			forwards: []string{"203.0.113.9"},
+			want:     "198.51.100.1",
+		},
+		{
+			name:     "Rightmost entry",
+			resolver: clientip.Resolver{TrustProxyHeaders: true},
+			remote:   "10.0.0.1:4000",
+			forwards: []string{"1.2.3.4, 203.0.113.9"},
+			want:     "203.0.113.9",
+		},
+		{
+			name:     "No header",
+			resolver: clientip.Resolver{TrustProxyHeaders: true},
+			remote:   "10.0.0.1:4000",
+			want:     "10.0.0.1",
+		},
+		{
+			name:     "Trusted proxies are skipped",
+			resolver: clientip.Resolver{TrustProxyHeaders: true, TrustedProxies: proxies},
+			remote:   "10.0.0.1:4000",
+			forwards: []string{"1.2.3.4, 203.0.113.9, 10.1.1.1", "192.0.2.7"},
+			want:     "203.0.113.9",
+		},
+		{
+			name:     "Connection not from a trusted proxy",
+			resolver: clientip.Resolver{TrustProxyHeaders: true, TrustedProxies: proxies},
+			remote:   "198.51.100.1:4000",
+			forwards: []string{"203.0.113.9"},
+			want:     "198.51.100.1",
+		},
+		{
+			name:     "Only trusted proxies",
+			resolver: clientip.Resolver{TrustProxyHeaders: true, TrustedProxies: proxies},
+			remote:   "10.0.0.1:4000",
+			forwards: []string{"10.2.2.2, 10.3.3.3"},
+			want:     "10.2.2.2",
+		},
+		{
+			name:     "Garbage",
+			resolver: clientip.Resolver{TrustProxyHeaders: true},
+			remote:   "10.0.0.1:4000",
+			forwards: []string{"1.2.3.4, not an address"},
+			want:     "10.0.0.1",
+		},
+		{
+			name:     "IPv6",
+			resolver: clientip.Resolver{TrustProxyHeaders: true},
+			remote:   "[::1]:4000",
+			forwards: []string{"2001:db8::1"},
+			want:     "2001:db8::1",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "/", nil)
+			request.RemoteAddr = tc.remote
+			for _, f := range tc.forwards {
+				request.Header.Add("X-Forwarded-For", f)
+			}
+			if got := tc.resolver.FromRequest(request); got != tc.want {
+				t.Errorf("Want '%s', got '%s'", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestParseNetworks(t *testing.T) {
+	networks := clientip.ParseNetworks("10.0.0.0/8, 192.0.2.7, ::1, bad, ")
+	if len(networks) != 3 {
+		t.Fatalf("Want 3 networks, got %v", networks)
+	}
+	if networks[1].String() != "192.0.2.7/32" || networks[2].String() != "::1/128" {
+		t.Errorf("Want single addresses as networks, got %v", networks)
+	}
+}
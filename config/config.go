@@ -0,0 +1,67 @@
+// Package config reads the server settings from environment variables. Every
+// variable is prefixed with PAMQ_ and falls back to a default when unset or
+// malformed.
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+const prefix = "PAMQ_"
+
+func lookup(key string) (string, bool) {
+	v, ok := os.LookupEnv(prefix + key)
+	if !ok || len(v) == 0 {
+		return "", false
+	}
+	return v, true
+}
+
+func String(key, def string) string {
+	if v, ok := lookup(key); ok {
+		return v
+	}
+	return def
+}
+
+func Int(key string, def int) int {
+	v, ok := lookup(key)
+	if !ok {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("Invalid value for %s%s, using %d: %v", prefix, key, def, err)
+		return def
+	}
+	return i
+}
+
+func Bool(key string, def bool) bool {
+	v, ok := lookup(key)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("Invalid value for %s%s, using %t: %v", prefix, key, def, err)
+		return def
+	}
+	return b
+}
+
+func Duration(key string, def time.Duration) time.Duration {
+	v, ok := lookup(key)
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("Invalid value for %s%s, using %s: %v", prefix, key, def, err)
+		return def
+	}
+	return d
+}
@@ -1,27 +1,52 @@
 package database
 
 import (
+	"PamQ/config"
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
 var DB *sql.DB
 
-//TODO use env variable
+// Defaults used when PAMQ_DATABASE_URL isn't set.
 const (
 	DB_USER = "postgres"
 	DB_NAME = "go_db_test"
 )
 
+// SchemaVersion is the version of the schema this code expects. A change to
+// database_tables.sql comes with a migrations/NNN_*.sql file upgrading
+// existing databases to version NNN, and bumps it and the version inserted
+// into schema_version.
+const SchemaVersion = 7
+
 func init() {
-	dbinfo := fmt.Sprintf("user=%s dbname=%s sslmode=disable", DB_USER, DB_NAME)
+	dbinfo := config.String("DATABASE_URL", fmt.Sprintf("user=%s dbname=%s sslmode=disable", DB_USER, DB_NAME))
 
 	var err error
 	DB, err = sql.Open("postgres", dbinfo)
 	if err != nil {
 		log.Fatal(err)
 	}
+	DB.SetMaxOpenConns(config.Int("DB_MAX_OPEN_CONNS", 25))
+	DB.SetMaxIdleConns(config.Int("DB_MAX_IDLE_CONNS", 25))
+	DB.SetConnMaxLifetime(config.Duration("DB_CONN_MAX_LIFETIME", 30*time.Minute))
+}
+
+// CheckSchema reports an error unless the database is reachable and its
+// schema is at least SchemaVersion.
+func CheckSchema(ctx context.Context) error {
+	var version sql.NullInt64
+	if err := DB.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_version`).Scan(&version); err != nil {
+		return err
+	}
+	if !version.Valid || version.Int64 < SchemaVersion {
+		return fmt.Errorf("database schema is at version %d, want %d", version.Int64, SchemaVersion)
+	}
+	return nil
 }
@@ -1,23 +1,62 @@
+-- The schema of a new database. Existing databases are upgraded with the
+-- files in migrations/, applied in order from the one after their version.
+-- Databases without schema_version are at version 0.
 CREATE TABLE userinfo (
     username    VARCHAR(50) PRIMARY KEY,
     email       VARCHAR(200) UNIQUE,
     password    VARCHAR(200),
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE,
+    display_name VARCHAR(100),
+    totp_secret VARCHAR(64),
+    totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    totp_last_step BIGINT NOT NULL DEFAULT 0,
+    is_admin    BOOLEAN NOT NULL DEFAULT FALSE,
     date_created TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
+CREATE TABLE category (
+    id          BIGSERIAL PRIMARY KEY,
+    parent_id   BIGINT REFERENCES category(id) ON DELETE RESTRICT,
+    name        VARCHAR(100) NOT NULL,
+    date_created TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX category_name_idx ON category (COALESCE(parent_id, 0), LOWER(name));
+
 CREATE TABLE quiz (
     id              BIGSERIAL PRIMARY KEY,
     creator         VARCHAR(50) NOT NULL REFERENCES userinfo ON DELETE CASCADE,
     name            VARCHAR(200) NOT NULL,
+    description     TEXT,
+    category_id     BIGINT REFERENCES category(id) ON DELETE SET NULL,
     grading_type    INT NOT NULL,   
     pass_fail       BOOLEAN NOT NULL,
     passing_score   INT,
     not_fail_text   VARCHAR(500),
     fail_text       VARCHAR(500),
     allowed_participations INT NOT NULL,
+    leaderboard     BOOLEAN NOT NULL DEFAULT FALSE,
+    leaderboard_show_names BOOLEAN NOT NULL DEFAULT FALSE,
+    opens_at        TIMESTAMP WITH TIME ZONE,
+    closes_at       TIMESTAMP WITH TIME ZONE,
+    version         INT NOT NULL DEFAULT 1,
+    updated_at      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
     date_created    TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
+CREATE INDEX quiz_date_created_idx ON quiz (date_created DESC, id DESC);
+CREATE INDEX quiz_name_idx ON quiz (name, id);
+CREATE INDEX quiz_search_idx ON quiz USING GIN (to_tsvector('simple', name || ' ' || COALESCE(description, '')));
+CREATE INDEX quiz_category_idx ON quiz (category_id);
+
+CREATE TABLE quiz_tag (
+    quiz_id     BIGINT REFERENCES quiz(id) ON DELETE CASCADE,
+    tag         VARCHAR(30) NOT NULL,
+    PRIMARY KEY (quiz_id, tag)
+);
+
+CREATE INDEX quiz_tag_tag_idx ON quiz_tag (tag);
+
 CREATE TABLE quiz_participation (
     id          BIGSERIAL PRIMARY KEY,
     quiz_id     BIGINT NOT NULL REFERENCES quiz ON DELETE CASCADE,
@@ -38,4 +77,108 @@ CREATE TABLE question (
     option3     VARCHAR(500),
     option4     VARCHAR(500),
     answer      VARCHAR(500)
-);
\ No newline at end of file
+);
+
+CREATE TABLE quiz_collaborator (
+    quiz_id     BIGINT NOT NULL REFERENCES quiz ON DELETE CASCADE,
+    username    VARCHAR(50) NOT NULL REFERENCES userinfo ON DELETE CASCADE,
+    role        INT NOT NULL,
+    added_by    VARCHAR(50) REFERENCES userinfo ON DELETE SET NULL,
+    date_created TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (quiz_id, username)
+);
+
+CREATE TABLE quiz_audit (
+    id          BIGSERIAL PRIMARY KEY,
+    quiz_id     BIGINT NOT NULL REFERENCES quiz ON DELETE CASCADE,
+    username    VARCHAR(50) REFERENCES userinfo ON DELETE SET NULL,
+    action      VARCHAR(50) NOT NULL,
+    detail      VARCHAR(500),
+    date_created TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE participation_answer (
+    participation_id BIGINT NOT NULL REFERENCES quiz_participation ON DELETE CASCADE,
+    question_id BIGINT NOT NULL REFERENCES question ON DELETE CASCADE,
+    answer      VARCHAR(500),
+    result      INT NOT NULL,
+    mark        FLOAT NOT NULL,
+    PRIMARY KEY (participation_id, question_id)
+);
+
+CREATE INDEX quiz_participation_leaderboard_idx ON quiz_participation (quiz_id, username, score DESC, date_created);
+
+CREATE TABLE user_session (
+    id          CHAR(64) PRIMARY KEY,
+    username    VARCHAR(50) NOT NULL REFERENCES userinfo ON DELETE CASCADE,
+    user_agent  VARCHAR(500),
+    ip          VARCHAR(100),
+    date_created TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_seen   TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at  TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX user_session_username_idx ON user_session (username);
+
+CREATE TABLE api_token (
+    id          BIGSERIAL PRIMARY KEY,
+    username    VARCHAR(50) NOT NULL REFERENCES userinfo ON DELETE CASCADE,
+    name        VARCHAR(100) NOT NULL,
+    token_hash  CHAR(64) NOT NULL UNIQUE,
+    prefix      VARCHAR(20) NOT NULL,
+    scopes      TEXT[] NOT NULL,
+    expires_at  TIMESTAMP WITH TIME ZONE,
+    last_used   TIMESTAMP WITH TIME ZONE,
+    date_created TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE user_identity (
+    provider    VARCHAR(50) NOT NULL,
+    subject     VARCHAR(255) NOT NULL,
+    username    VARCHAR(50) NOT NULL REFERENCES userinfo ON DELETE CASCADE,
+    email       VARCHAR(200),
+    date_created TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE TABLE rate_limit_bucket (
+    key         VARCHAR(300) PRIMARY KEY,
+    tat         TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Failed logins in a row of a username from one client IP, which lock out
+-- that pair for a while when there are too many.
+CREATE TABLE login_failure (
+    username     VARCHAR(50) REFERENCES userinfo(username) ON DELETE CASCADE ON UPDATE CASCADE,
+    ip           VARCHAR(64) NOT NULL,
+    failures     INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (username, ip)
+);
+
+CREATE TABLE user_recovery_code (
+    username    VARCHAR(50) REFERENCES userinfo(username) ON DELETE CASCADE ON UPDATE CASCADE,
+    code_hash   CHAR(64) NOT NULL,
+    used_at     TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (username, code_hash)
+);
+
+-- Responses to quiz submissions sent with an Idempotency-Key, replayed when
+-- the same submission is retried. A NULL status marks one still in progress.
+CREATE TABLE idempotency_key (
+    username     VARCHAR(50) REFERENCES userinfo(username) ON DELETE CASCADE ON UPDATE CASCADE,
+    key          VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status       INT,
+    response     BYTEA,
+    date_created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (username, key)
+);
+
+CREATE TABLE schema_version (
+    version     INT PRIMARY KEY,
+    date_applied TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- The version of the newest file in migrations/.
+INSERT INTO schema_version (version) VALUES (7);
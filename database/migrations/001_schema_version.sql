@@ -0,0 +1,98 @@
+-- Brings a database created before schema_version, from the original schema
+-- or any later database_tables.sql without it, to version 1. Every change is
+-- guarded so that the parts a database already has are kept.
+BEGIN;
+
+ALTER TABLE userinfo ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE userinfo ADD COLUMN IF NOT EXISTS display_name VARCHAR(100);
+ALTER TABLE userinfo ADD COLUMN IF NOT EXISTS failed_logins INT NOT NULL DEFAULT 0;
+ALTER TABLE userinfo ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE;
+ALTER TABLE userinfo ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64);
+ALTER TABLE userinfo ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE userinfo ADD COLUMN IF NOT EXISTS totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE quiz ADD COLUMN IF NOT EXISTS leaderboard BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE quiz ADD COLUMN IF NOT EXISTS leaderboard_show_names BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS quiz_collaborator (
+    quiz_id     BIGINT NOT NULL REFERENCES quiz ON DELETE CASCADE,
+    username    VARCHAR(50) NOT NULL REFERENCES userinfo ON DELETE CASCADE,
+    role        INT NOT NULL,
+    added_by    VARCHAR(50) REFERENCES userinfo ON DELETE SET NULL,
+    date_created TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (quiz_id, username)
+);
+
+CREATE TABLE IF NOT EXISTS quiz_audit (
+    id          BIGSERIAL PRIMARY KEY,
+    quiz_id     BIGINT NOT NULL REFERENCES quiz ON DELETE CASCADE,
+    username    VARCHAR(50) REFERENCES userinfo ON DELETE SET NULL,
+    action      VARCHAR(50) NOT NULL,
+    detail      VARCHAR(500),
+    date_created TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS participation_answer (
+    participation_id BIGINT NOT NULL REFERENCES quiz_participation ON DELETE CASCADE,
+    question_id BIGINT NOT NULL REFERENCES question ON DELETE CASCADE,
+    answer      VARCHAR(500),
+    result      INT NOT NULL,
+    mark        FLOAT NOT NULL,
+    PRIMARY KEY (participation_id, question_id)
+);
+
+CREATE INDEX IF NOT EXISTS quiz_participation_leaderboard_idx ON quiz_participation (quiz_id, username, score DESC, date_created);
+
+CREATE TABLE IF NOT EXISTS user_session (
+    id          CHAR(64) PRIMARY KEY,
+    username    VARCHAR(50) NOT NULL REFERENCES userinfo ON DELETE CASCADE,
+    user_agent  VARCHAR(500),
+    ip          VARCHAR(100),
+    date_created TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    last_seen   TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at  TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS user_session_username_idx ON user_session (username);
+
+CREATE TABLE IF NOT EXISTS api_token (
+    id          BIGSERIAL PRIMARY KEY,
+    username    VARCHAR(50) NOT NULL REFERENCES userinfo ON DELETE CASCADE,
+    name        VARCHAR(100) NOT NULL,
+    token_hash  CHAR(64) NOT NULL UNIQUE,
+    prefix      VARCHAR(20) NOT NULL,
+    scopes      TEXT[] NOT NULL,
+    expires_at  TIMESTAMP WITH TIME ZONE,
+    last_used   TIMESTAMP WITH TIME ZONE,
+    date_created TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS user_identity (
+    provider    VARCHAR(50) NOT NULL,
+    subject     VARCHAR(255) NOT NULL,
+    username    VARCHAR(50) NOT NULL REFERENCES userinfo ON DELETE CASCADE,
+    email       VARCHAR(200),
+    date_created TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE TABLE IF NOT EXISTS rate_limit_bucket (
+    key         VARCHAR(300) PRIMARY KEY,
+    tat         TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_code (
+    username    VARCHAR(50) REFERENCES userinfo(username) ON DELETE CASCADE ON UPDATE CASCADE,
+    code_hash   CHAR(64) NOT NULL,
+    used_at     TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (username, code_hash)
+);
+
+CREATE TABLE IF NOT EXISTS schema_version (
+    version     INT PRIMARY KEY,
+    date_applied TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO schema_version (version) VALUES (1);
+
+COMMIT;
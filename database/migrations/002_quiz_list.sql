@@ -0,0 +1,13 @@
+-- Opening times of quizzes and the indexes of the quiz list.
+BEGIN;
+
+ALTER TABLE quiz ADD COLUMN opens_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE quiz ADD COLUMN closes_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX quiz_date_created_idx ON quiz (date_created DESC, id DESC);
+CREATE INDEX quiz_name_idx ON quiz (name, id);
+CREATE INDEX quiz_search_idx ON quiz USING GIN (to_tsvector('simple', name));
+
+INSERT INTO schema_version (version) VALUES (2);
+
+COMMIT;
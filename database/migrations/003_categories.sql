@@ -0,0 +1,32 @@
+-- Quiz descriptions, tags and the category tree managed by admins.
+BEGIN;
+
+ALTER TABLE userinfo ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE category (
+    id          BIGSERIAL PRIMARY KEY,
+    parent_id   BIGINT REFERENCES category(id) ON DELETE RESTRICT,
+    name        VARCHAR(100) NOT NULL,
+    date_created TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX category_name_idx ON category (COALESCE(parent_id, 0), LOWER(name));
+
+ALTER TABLE quiz ADD COLUMN description TEXT;
+ALTER TABLE quiz ADD COLUMN category_id BIGINT REFERENCES category(id) ON DELETE SET NULL;
+
+DROP INDEX quiz_search_idx;
+CREATE INDEX quiz_search_idx ON quiz USING GIN (to_tsvector('simple', name || ' ' || COALESCE(description, '')));
+CREATE INDEX quiz_category_idx ON quiz (category_id);
+
+CREATE TABLE quiz_tag (
+    quiz_id     BIGINT REFERENCES quiz(id) ON DELETE CASCADE,
+    tag         VARCHAR(30) NOT NULL,
+    PRIMARY KEY (quiz_id, tag)
+);
+
+CREATE INDEX quiz_tag_tag_idx ON quiz_tag (tag);
+
+INSERT INTO schema_version (version) VALUES (3);
+
+COMMIT;
@@ -0,0 +1,9 @@
+-- Versions of quizzes, used for their ETags and If-Match.
+BEGIN;
+
+ALTER TABLE quiz ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE quiz ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+INSERT INTO schema_version (version) VALUES (4);
+
+COMMIT;
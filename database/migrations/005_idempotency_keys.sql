@@ -0,0 +1,16 @@
+-- Responses to quiz submissions sent with an Idempotency-Key.
+BEGIN;
+
+CREATE TABLE idempotency_key (
+    username     VARCHAR(50) REFERENCES userinfo(username) ON DELETE CASCADE ON UPDATE CASCADE,
+    key          VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status       INT,
+    response     BYTEA,
+    date_created TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (username, key)
+);
+
+INSERT INTO schema_version (version) VALUES (5);
+
+COMMIT;
@@ -0,0 +1,9 @@
+-- Emails are compared without case and stored in lower case. This fails when
+-- two accounts use the same email in different cases; merge them first.
+BEGIN;
+
+UPDATE userinfo SET email = LOWER(email) WHERE email <> LOWER(email);
+
+INSERT INTO schema_version (version) VALUES (6);
+
+COMMIT;
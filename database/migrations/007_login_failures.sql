@@ -0,0 +1,18 @@
+-- Failed logins are counted per username and client IP instead of per
+-- account, so that guessing from one address can't lock out the owner.
+BEGIN;
+
+CREATE TABLE login_failure (
+    username     VARCHAR(50) REFERENCES userinfo(username) ON DELETE CASCADE ON UPDATE CASCADE,
+    ip           VARCHAR(64) NOT NULL,
+    failures     INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (username, ip)
+);
+
+ALTER TABLE userinfo DROP COLUMN failed_logins;
+ALTER TABLE userinfo DROP COLUMN locked_until;
+
+INSERT INTO schema_version (version) VALUES (7);
+
+COMMIT;
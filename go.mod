@@ -4,8 +4,6 @@ go 1.14
 
 require (
 	github.com/gorilla/mux v1.8.0
-	github.com/gorilla/sessions v1.2.1
 	github.com/lib/pq v1.8.0
-	github.com/mitchellh/mapstructure v1.3.3
 	golang.org/x/crypto v0.0.0-20200820211705-5c72a883971a
 )
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"PamQ/passwords"
+	"PamQ/sessions"
+	"PamQ/tokens"
+	"fmt"
+	"net/http"
+)
+
+func VerifyEmailHandler(w http.ResponseWriter, r *http.Request) error {
+	var req TokenRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+
+	user, err := verifyUserToken(req.Token, verifyEmailPurpose, emailBinding)
+	if err == tokens.ErrExpired {
+		return NewClientError(err, http.StatusBadRequest, "Verification link has expired.")
+	} else if err != nil {
+		return NewClientError(err, http.StatusBadRequest, "Invalid verification link.")
+	}
+
+	if err := setEmailVerified(user.Username, user.Email); err != nil {
+		return NewServerError(err, 500, "Error saving data to database")
+	}
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Email verified.", "email": user.Email})
+}
+
+func ResendVerificationHandler(w http.ResponseWriter, r *http.Request) error {
+	if !sessions.IsLoggedIn(r) {
+		return NewClientError(nil, http.StatusUnauthorized, "Please login first")
+	}
+	username, ok := sessions.GetUsername(r)
+	if !ok {
+		return NewServerError(nil, 500, "Error getting username from session")
+	}
+
+	user, err := getUserByUsername(username)
+	if err != nil {
+		return err
+	}
+	if len(user.Email) == 0 {
+		return NewClientError(nil, http.StatusBadRequest, "Please set an email first.")
+	}
+	if user.EmailVerified {
+		return NewClientError(nil, http.StatusBadRequest, "Email is already verified.")
+	}
+	if err := sendVerificationEmail(user); err != nil {
+		return NewServerError(err, 500, "Error sending email")
+	}
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Verification email sent."})
+}
+
+// ForgotPasswordHandler always answers the same way so that it can't be used
+// to find out which emails have an account.
+func ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) error {
+	var req EmailRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+	if len(req.Email) == 0 {
+		return NewClientError(ErrorMissingField("email"), http.StatusBadRequest, "Invalid form data: email is required.")
+	}
+
+	if user, err := getUserByEmail(req.Email); err == nil {
+		if err := sendPasswordResetEmail(user); err != nil {
+			requestLogger(r).Error("error sending password reset email", "user", user.Username, "cause", causes(err))
+		}
+	}
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"message": "If an account uses this email, a password reset link has been sent to it."})
+}
+
+func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) error {
+	var req PasswordReset
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+	if err := validateNewPassword(req.Password, req.PasswordConfirm); err != nil {
+		return NewClientError(err, http.StatusBadRequest, fmt.Sprintf("Invalid form data: %s", err.Error()))
+	}
+
+	user, err := verifyUserToken(req.Token, resetPasswordPurpose, passwordBinding)
+	if err == tokens.ErrExpired {
+		return NewClientError(err, http.StatusBadRequest, "Password reset link has expired.")
+	} else if err != nil {
+		return NewClientError(err, http.StatusBadRequest, "Invalid password reset link.")
+	}
+
+	hashedPass, err := passwords.Default.Hash(req.Password)
+	if err != nil {
+		return NewServerError(err, 500, "Error hashing password")
+	}
+	if err := setUserPass(user.Username, hashedPass); err != nil {
+		return NewServerError(err, 500, "Error saving data to database")
+	}
+	if err := revokeAccess(user.Username); err != nil {
+		requestLogger(r).Error("error revoking access after password reset", "user", user.Username, "cause", causes(err))
+	}
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Password changed. You have been logged out everywhere and your API tokens have been revoked."})
+}
+
+func loggedInUser(r *http.Request) (*User, error) {
+	if !sessions.IsLoggedIn(r) {
+		return nil, NewClientError(nil, http.StatusUnauthorized, "Please login first")
+	}
+	username, ok := sessions.GetUsername(r)
+	if !ok {
+		return nil, NewServerError(nil, 500, "Error getting username from session")
+	}
+	return getUserByUsername(username)
+}
+
+func MeHandler(w http.ResponseWriter, r *http.Request) error {
+	user, err := loggedInUser(r)
+	if err != nil {
+		return err
+	}
+
+	if r.Method == http.MethodPatch {
+		var update ProfileUpdate
+		if err := decodeJSON(w, r, &update); err != nil {
+			return err
+		}
+		if err := update.validate(); err != nil {
+			return NewClientError(err, http.StatusBadRequest, fmt.Sprintf("Invalid form data: %s", err.Error()))
+		}
+		if update.Email != nil || update.Password != nil {
+			// Accounts without a password may set their first one.
+			if len(user.HashedPassword) != 0 && len(update.OldPassword) == 0 {
+				err := ErrorMissingField("old_password")
+				return NewClientError(err, http.StatusBadRequest, fmt.Sprintf("Invalid form data: %s", err.Error()))
+			}
+			if err := confirmUser(r, user, update.OldPassword); err != nil {
+				return err
+			}
+		}
+
+		emailChanged, err := update.apply(user)
+		if err != nil {
+			return err
+		}
+		if update.Password != nil {
+			if err := sessions.LogoutOthers(r, user.Username); err != nil {
+				requestLogger(r).Error("error ending sessions after password change", "user", user.Username, "cause", causes(err))
+			}
+		}
+		if emailChanged {
+			if err := sendVerificationEmail(user); err != nil {
+				requestLogger(r).Error("error sending verification email", "user", user.Username, "cause", causes(err))
+			}
+		}
+	}
+
+	return writeJSON(w, http.StatusOK, user)
+}
+
+func DeleteAccountHandler(w http.ResponseWriter, r *http.Request) error {
+	user, err := loggedInUser(r)
+	if err != nil {
+		return err
+	}
+
+	var req AccountDeletion
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+	if err := confirmUser(r, user, req.Password); err != nil {
+		return err
+	}
+
+	if err := deleteUser(user.Username); err != nil {
+		return err
+	}
+	if err := sessions.Logout(w, r); err != nil {
+		requestLogger(r).Error("error logging out deleted user", "user", user.Username, "cause", causes(err))
+	}
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Account deleted."})
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"PamQ/apitokens"
+	"PamQ/config"
+	db "PamQ/database"
+	"PamQ/mail"
+	"PamQ/passwords"
+	"PamQ/sessions"
+	"PamQ/tokens"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	verifyEmailPurpose   = "verify-email"
+	resetPasswordPurpose = "reset-password"
+	verifyEmailTTL       = 48 * time.Hour
+	resetPasswordTTL     = time.Hour
+)
+
+// appURL is where the front-end is served; links in emails point to it.
+var appURL = config.String("APP_URL", "http://localhost:8080")
+
+type TokenRequest struct {
+	Token string `json:"token"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordReset struct {
+	Token           string `json:"token"`
+	Password        string `json:"password"`
+	PasswordConfirm string `json:"password_confirm"`
+}
+
+func appLink(path, token string) string {
+	return fmt.Sprintf("%s%s?token=%s", appURL, path, url.QueryEscape(token))
+}
+
+// A verification token is bound to the email it confirms and a reset token to
+// the password it replaces, so changing either invalidates the link.
+func emailBinding(u *User) string    { return u.Email }
+func passwordBinding(u *User) string { return u.HashedPassword }
+
+// sendVerificationEmail mails a link to confirm the current email of user.
+// Changing the email invalidates the link.
+func sendVerificationEmail(user *User) error {
+	token := tokens.Default.Sign(verifyEmailPurpose, user.Username, emailBinding(user), verifyEmailTTL)
+	return mail.Default.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Verify your PamQ email",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email by opening this link within %d hours:\n\n%s\n",
+			user.Username, int(verifyEmailTTL.Hours()), appLink("/verify-email", token)),
+	})
+}
+
+// sendPasswordResetEmail mails a password reset link. The link stops working
+// once the password has been changed.
+func sendPasswordResetEmail(user *User) error {
+	token := tokens.Default.Sign(resetPasswordPurpose, user.Username, passwordBinding(user), resetPasswordTTL)
+	return mail.Default.Send(mail.Message{
+		To:      user.Email,
+		Subject: "Reset your PamQ password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset your password. If it was you, open this link within %d minutes:\n\n%s\n\nOtherwise you can ignore this email.\n",
+			user.Username, int(resetPasswordTTL.Minutes()), appLink("/reset-password", token)),
+	})
+}
+
+// verifyUserToken checks token for purpose against the binding of the user
+// it was issued to.
+func verifyUserToken(token, purpose string, binding func(*User) string) (*User, error) {
+	username, err := tokens.Subject(token)
+	if err != nil {
+		return nil, err
+	}
+	user, err := getUserByUsername(username)
+	if err != nil {
+		return nil, tokens.ErrInvalid
+	}
+	if err := checkUserToken(user, token, purpose, binding); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func checkUserToken(user *User, token, purpose string, binding func(*User) string) error {
+	_, err := tokens.Default.Verify(token, purpose, binding(user))
+	return err
+}
+
+// revokeAccess ends every session of username and revokes their API tokens.
+// A password reset may recover an account someone else got into, so nothing
+// granted before it is kept. Changing the password or logging out of all
+// devices keeps the tokens, which are managed on their own.
+func revokeAccess(username string) error {
+	if err := sessions.Store.DeleteUser(username, ""); err != nil {
+		return err
+	}
+	return apitokens.RevokeAll(username)
+}
+
+func setEmailVerified(username, email string) error {
+	db := db.DB
+	_, err := db.Exec(`UPDATE userinfo SET email_verified=TRUE WHERE username=$1 AND email=$2`, username, email)
+	return err
+}
+
+// ProfileUpdate holds the fields of the profile to change; nil fields are
+// left as they are. Changing the email or the password requires the current
+// password.
+type ProfileUpdate struct {
+	DisplayName     *string `json:"display_name"`
+	Email           *string `json:"email"`
+	Password        *string `json:"password"`
+	PasswordConfirm string  `json:"password_confirm"`
+	OldPassword     string  `json:"old_password"`
+}
+
+type AccountDeletion struct {
+	Password string `json:"password"`
+}
+
+func (p *ProfileUpdate) validate() error {
+	var errs FieldErrors
+	if p.DisplayName != nil {
+		*p.DisplayName = strings.TrimSpace(*p.DisplayName)
+		if len(*p.DisplayName) > 100 {
+			errs.add(invalidField("display_name", "Please enter a display name of at most 100 characters."))
+		}
+	}
+	if p.Email != nil {
+		*p.Email = strings.ToLower(*p.Email)
+		if !validateEmail(*p.Email) {
+			errs.add(invalidField("email", "Please enter a valid email."))
+		}
+	}
+	if p.Password != nil {
+		errs.add(validateNewPassword(*p.Password, p.PasswordConfirm))
+	}
+	return errs.err()
+}
+
+// apply saves the update and reports whether the email changed.
+func (p *ProfileUpdate) apply(user *User) (bool, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return false, NewServerError(err, 500, "Error starting database transaction")
+	}
+	defer tx.Rollback()
+
+	if p.DisplayName != nil {
+		if _, err := tx.Exec(`UPDATE userinfo SET display_name=NULLIF($2, '') WHERE username=$1`, user.Username, *p.DisplayName); err != nil {
+			return false, NewServerError(err, 500, "Error saving data to database")
+		}
+		user.DisplayName = *p.DisplayName
+	}
+
+	emailChanged := p.Email != nil && *p.Email != user.Email
+	if emailChanged {
+		if _, err := tx.Exec(`UPDATE userinfo SET email=LOWER($2), email_verified=FALSE WHERE username=$1`, user.Username, *p.Email); err != nil {
+			return false, NewServerError(err, 500, "Error saving data to database")
+		}
+		user.Email, user.EmailVerified = *p.Email, false
+	}
+
+	if p.Password != nil {
+		hashedPass, err := passwords.Default.Hash(*p.Password)
+		if err != nil {
+			return false, NewServerError(err, 500, "Error hashing password")
+		}
+		if _, err := tx.Exec(`UPDATE userinfo SET password=$2 WHERE username=$1`, user.Username, hashedPass); err != nil {
+			return false, NewServerError(err, 500, "Error saving data to database")
+		}
+		user.HashedPassword = hashedPass
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, NewServerError(err, 500, "Error saving data to database")
+	}
+	return emailChanged, nil
+}
+
+func deleteUser(username string) error {
+	db := db.DB
+	if _, err := db.Exec(`DELETE FROM userinfo WHERE username=$1`, username); err != nil {
+		return NewServerError(err, 500, "Error deleting user from database")
+	}
+	return nil
+}
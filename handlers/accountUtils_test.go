@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"PamQ/mail"
+	"PamQ/sessions"
+	"PamQ/tokens"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type sentMail struct {
+	messages []mail.Message
+}
+
+func (m *sentMail) Send(msg mail.Message) error {
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// mailedToken sends a mail with send and returns the token of its link.
+func mailedToken(t *testing.T, send func() error) string {
+	t.Helper()
+	outbox := &sentMail{}
+	defer func(m mail.Mailer) { mail.Default = m }(mail.Default)
+	mail.Default = outbox
+
+	if err := send(); err != nil {
+		t.Fatal(err)
+	}
+	if len(outbox.messages) != 1 {
+		t.Fatalf("Want 1 mail, got %d", len(outbox.messages))
+	}
+	match := regexp.MustCompile(`\?token=(\S+)`).FindStringSubmatch(outbox.messages[0].Body)
+	if match == nil {
+		t.Fatalf("No link in %q", outbox.messages[0].Body)
+	}
+	token, err := url.QueryUnescape(match[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerificationToken(t *testing.T) {
+	user := &User{Username: "alice", Email: "alice@example.com", HashedPassword: "hash"}
+	token := mailedToken(t, func() error { return sendVerificationEmail(user) })
+
+	if err := checkUserToken(user, token, verifyEmailPurpose, emailBinding); err != nil {
+		t.Errorf("Want the token to verify, got %v", err)
+	}
+	if err := checkUserToken(user, token, resetPasswordPurpose, passwordBinding); err != tokens.ErrInvalid {
+		t.Errorf("Want a verification token to not reset the password, got %v", err)
+	}
+	changed := *user
+	changed.Email = "mallory@example.com"
+	if err := checkUserToken(&changed, token, verifyEmailPurpose, emailBinding); err != tokens.ErrInvalid {
+		t.Errorf("Want the token to stop working once the email changed, got %v", err)
+	}
+}
+
+func TestResetToken(t *testing.T) {
+	user := &User{Username: "alice", Email: "alice@example.com", HashedPassword: "hash"}
+	token := mailedToken(t, func() error { return sendPasswordResetEmail(user) })
+
+	if err := checkUserToken(user, token, resetPasswordPurpose, passwordBinding); err != nil {
+		t.Errorf("Want the token to verify, got %v", err)
+	}
+	if err := checkUserToken(user, token, verifyEmailPurpose, emailBinding); err != tokens.ErrInvalid {
+		t.Errorf("Want a reset token to not verify the email, got %v", err)
+	}
+	changed := *user
+	changed.HashedPassword = "new hash"
+	if err := checkUserToken(&changed, token, resetPasswordPurpose, passwordBinding); err != tokens.ErrInvalid {
+		t.Errorf("Want the token to be single use, got %v", err)
+	}
+}
+
+func TestProfileUpdateValidate(t *testing.T) {
+	name, email := "  Alice  ", "Alice@Example.COM"
+	update := ProfileUpdate{DisplayName: &name, Email: &email}
+	if err := update.validate(); err != nil {
+		t.Fatalf("Want no error, got %v", err)
+	}
+	if *update.DisplayName != "Alice" {
+		t.Errorf("Want the display name trimmed, got %q", *update.DisplayName)
+	}
+	if *update.Email != "alice@example.com" {
+		t.Errorf("Want the email in lower case, got %q", *update.Email)
+	}
+
+	long, bad, weak := strings.Repeat("a", 101), "alice", "short"
+	update = ProfileUpdate{DisplayName: &long, Email: &bad, Password: &weak, PasswordConfirm: "other"}
+	var errs FieldErrors
+	if !errors.As(update.validate(), &errs) {
+		t.Fatal("Want field errors")
+	}
+	fields := make(map[string]bool)
+	for _, fieldErr := range errs {
+		fields[fieldErr.Field] = true
+	}
+	for _, field := range []string{"display_name", "email", "password", "password_confirm"} {
+		if !fields[field] {
+			t.Errorf("Want an error on %s, got %v", field, errs)
+		}
+	}
+}
+
+func TestMeHandlerRequiresLogin(t *testing.T) {
+	defer func(s sessions.SessionStore) { sessions.Store = s }(sessions.Store)
+	sessions.Store = sessions.NewMemoryStore()
+
+	r := httptest.NewRequest(http.MethodPatch, "/api/me", strings.NewReader(`{"display_name": "Alice"}`))
+	var herr *HTTPError
+	if err := MeHandler(httptest.NewRecorder(), r); !errors.As(err, &herr) || herr.Status != http.StatusUnauthorized {
+		t.Errorf("Want 401, got %v", err)
+	}
+}
+
+func TestRevokeAccess(t *testing.T) {
+	defer func(s sessions.SessionStore) { sessions.Store = s }(sessions.Store)
+	store := sessions.NewMemoryStore()
+	sessions.Store = store
+	store.Create(&sessions.Session{ID: "laptop", Username: "alice"})
+	store.Create(&sessions.Session{ID: "phone", Username: "alice"})
+	store.Create(&sessions.Session{ID: "other", Username: "bob"})
+	d, restore := useStubDB(t, nil)
+	defer restore()
+
+	if err := revokeAccess("alice"); err != nil {
+		t.Fatal(err)
+	}
+	if list, _ := store.List("alice"); len(list) != 0 {
+		t.Errorf("Want every session ended, got %v", list)
+	}
+	if list, _ := store.List("bob"); len(list) != 1 {
+		t.Errorf("Want the sessions of others kept, got %v", list)
+	}
+	deleted := d.ran("DELETE FROM api_token")
+	if len(deleted) != 1 || len(deleted[0].args) != 1 || deleted[0].args[0] != "alice" {
+		t.Errorf("Want the API tokens of alice revoked, got %v", deleted)
+	}
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	db "PamQ/database"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ItemStats holds the classical test theory statistics of one question.
+type ItemStats struct {
+	QuestionID int    `json:"question_id"`
+	Statement  string `json:"statement"`
+	Responses  int    `json:"responses"`
+	// Difficulty is the proportion of correct answers (p-value).
+	Difficulty *float64 `json:"difficulty"`
+	// Discrimination is the point-biserial correlation between answering
+	// the question correctly and the score on the remaining questions.
+	Discrimination *float64 `json:"discrimination"`
+	// Options counts how often each option was chosen, "" being no answer.
+	// It is only reported for multichoice questions.
+	Options map[string]int `json:"options,omitempty"`
+}
+
+type QuizAnalysis struct {
+	QuizID         int         `json:"quiz_id"`
+	Participations int         `json:"participations"`
+	Items          []ItemStats `json:"items"`
+	CronbachAlpha  *float64    `json:"cronbach_alpha"`
+	KR20           *float64    `json:"kr20"`
+}
+
+// getAnswersByParticipation returns the recorded answers of every
+// participation of a quiz, keyed by participation and then question id.
+func getAnswersByParticipation(quizID int) (map[int]map[int]ParticipationAnswer, error) {
+	db := db.DB
+	rows, err := db.Query(`SELECT a.participation_id, a.question_id, a.answer, a.result, a.mark
+		FROM participation_answer a JOIN quiz_participation p ON p.id = a.participation_id
+		WHERE p.quiz_id=$1`, quizID)
+	if err != nil {
+		return nil, NewServerError(err, 500, "Error fetching data from database")
+	}
+	defer rows.Close()
+
+	answers := map[int]map[int]ParticipationAnswer{}
+	for rows.Next() {
+		var a ParticipationAnswer
+		if err := rows.Scan(&a.ParticipationID, &a.QuestionID, &a.Answer, &a.Result, &a.Mark); err != nil {
+			return nil, NewServerError(err, 500, "Error fetching data from database")
+		}
+		if answers[a.ParticipationID] == nil {
+			answers[a.ParticipationID] = map[int]ParticipationAnswer{}
+		}
+		answers[a.ParticipationID][a.QuestionID] = a
+	}
+	return answers, nil
+}
+
+// analyzeQuiz computes item statistics for the questions that have an answer
+// key. Item statistics use every participation that answered the question;
+// reliability only uses participations that answered all of them.
+func analyzeQuiz(quiz Quiz, answers map[int]map[int]ParticipationAnswer) QuizAnalysis {
+	analysis := QuizAnalysis{QuizID: quiz.Id, Participations: len(answers), Items: []ItemStats{}}
+
+	var items []Question
+	for _, q := range quiz.Questions {
+		if len(strings.TrimSpace(q.Answer)) != 0 {
+			items = append(items, q)
+		}
+	}
+
+	totals := map[int]float64{}
+	for pid, byQuestion := range answers {
+		for _, q := range items {
+			totals[pid] += byQuestion[q.Id].Mark
+		}
+	}
+
+	for _, q := range items {
+		stats := ItemStats{QuestionID: q.Id, Statement: q.Statement}
+		if q.QType == MultiChoice {
+			stats.Options = map[string]int{"": 0}
+			for i := 1; i <= 4; i++ {
+				stats.Options[strconv.Itoa(i)] = 0
+			}
+		}
+
+		var correct, rest []float64
+		for pid, byQuestion := range answers {
+			a, ok := byQuestion[q.Id]
+			if !ok {
+				continue
+			}
+			x := 0.0
+			if a.Result == Correct {
+				x = 1
+			}
+			correct = append(correct, x)
+			rest = append(rest, totals[pid]-a.Mark)
+			if stats.Options != nil {
+				stats.Options[a.Answer]++
+			}
+		}
+
+		stats.Responses = len(correct)
+		if stats.Responses > 0 {
+			p := mean(correct)
+			stats.Difficulty = &p
+		}
+		stats.Discrimination = correlation(correct, rest)
+		analysis.Items = append(analysis.Items, stats)
+	}
+
+	// Reliability needs a complete participants by items matrix.
+	var marks, scores [][]float64
+	for _, byQuestion := range answers {
+		var m, s []float64
+		for _, q := range items {
+			a, ok := byQuestion[q.Id]
+			if !ok {
+				break
+			}
+			m = append(m, a.Mark)
+			if a.Result == Correct {
+				s = append(s, 1)
+			} else {
+				s = append(s, 0)
+			}
+		}
+		if len(m) == len(items) {
+			marks = append(marks, m)
+			scores = append(scores, s)
+		}
+	}
+	analysis.CronbachAlpha = cronbachAlpha(marks)
+	analysis.KR20 = cronbachAlpha(scores)
+
+	return analysis
+}
+
+func mean(xs []float64) float64 {
+	sum := 0.0
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+func variance(xs []float64) float64 {
+	m := mean(xs)
+	sum := 0.0
+	for _, x := range xs {
+		sum += (x - m) * (x - m)
+	}
+	return sum / float64(len(xs))
+}
+
+// correlation returns the Pearson correlation of xs and ys, or nil when it is
+// undefined.
+func correlation(xs, ys []float64) *float64 {
+	if len(xs) < 2 {
+		return nil
+	}
+	mx, my := mean(xs), mean(ys)
+	var cov, vx, vy float64
+	for i := range xs {
+		cov += (xs[i] - mx) * (ys[i] - my)
+		vx += (xs[i] - mx) * (xs[i] - mx)
+		vy += (ys[i] - my) * (ys[i] - my)
+	}
+	if vx == 0 || vy == 0 {
+		return nil
+	}
+	r := cov / math.Sqrt(vx*vy)
+	return &r
+}
+
+// cronbachAlpha computes coefficient alpha over a participants by items
+// matrix. On dichotomous (0/1) items it equals KR-20.
+func cronbachAlpha(matrix [][]float64) *float64 {
+	if len(matrix) < 2 || len(matrix[0]) < 2 {
+		return nil
+	}
+	k := len(matrix[0])
+
+	totals := make([]float64, len(matrix))
+	itemVariances := 0.0
+	for j := 0; j < k; j++ {
+		column := make([]float64, len(matrix))
+		for i, row := range matrix {
+			column[i] = row[j]
+			totals[i] += row[j]
+		}
+		itemVariances += variance(column)
+	}
+
+	totalVariance := variance(totals)
+	if totalVariance == 0 {
+		return nil
+	}
+	alpha := float64(k) / float64(k-1) * (1 - itemVariances/totalVariance)
+	return &alpha
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+)
+
+func multiChoice(id int) Question {
+	return Question{Id: id, QType: MultiChoice, Statement: "?", Option1: "a", Option2: "b", Answer: "1"}
+}
+
+// answersOf builds the answers of participations from rows of 0/1 results,
+// one column per question id in ids.
+func answersOf(ids []int, rows ...[]int) map[int]map[int]ParticipationAnswer {
+	answers := map[int]map[int]ParticipationAnswer{}
+	for i, row := range rows {
+		answers[i+1] = map[int]ParticipationAnswer{}
+		for j, x := range row {
+			a := ParticipationAnswer{ParticipationID: i + 1, QuestionID: ids[j], Answer: "2", Result: Wrong}
+			if x == 1 {
+				a.Answer, a.Result, a.Mark = "1", Correct, 1
+			}
+			answers[i+1][ids[j]] = a
+		}
+	}
+	return answers
+}
+
+func checkStat(t *testing.T, name string, got *float64, want float64) {
+	t.Helper()
+	if math.IsNaN(want) {
+		if got != nil {
+			t.Errorf("Want no %s, got %v", name, *got)
+		}
+		return
+	}
+	if got == nil {
+		t.Errorf("Want %s %v, got none", name, want)
+	} else if math.Abs(*got-want) > 1e-4 {
+		t.Errorf("Want %s %v, got %v", name, want, *got)
+	}
+}
+
+func TestAnalyzeQuiz(t *testing.T) {
+	none := math.NaN()
+	ids := []int{1, 2, 3}
+	quiz := Quiz{Id: 7, Questions: []Question{multiChoice(1), multiChoice(2), multiChoice(3),
+		// Questions without an answer key aren't analyzed.
+		{Id: 4, QType: ShortAnswer, Statement: "Why?"}}}
+
+	t.Run("Known results", func(t *testing.T) {
+		a := analyzeQuiz(quiz, answersOf(ids, []int{1, 1, 1}, []int{1, 1, 0}, []int{1, 0, 0}, []int{0, 0, 0}))
+		if a.Participations != 4 || len(a.Items) != 3 {
+			t.Fatalf("Want 4 participations and 3 items, got %d and %d", a.Participations, len(a.Items))
+		}
+		checkStat(t, "difficulty", a.Items[0].Difficulty, 0.75)
+		checkStat(t, "difficulty", a.Items[1].Difficulty, 0.5)
+		checkStat(t, "difficulty", a.Items[2].Difficulty, 0.25)
+		// Item 1 against the rest scores 2, 1, 0, 0.
+		checkStat(t, "discrimination", a.Items[0].Discrimination, 0.75/math.Sqrt(0.75*2.75))
+		// Item variances sum to 0.625 and total scores 3, 2, 1, 0 vary by 1.25.
+		checkStat(t, "KR-20", a.KR20, 0.75)
+		checkStat(t, "alpha", a.CronbachAlpha, 0.75)
+		if a.Items[0].Responses != 4 || a.Items[0].Options["1"] != 3 || a.Items[0].Options["2"] != 1 || a.Items[0].Options["3"] != 0 {
+			t.Errorf("Want 4 responses, 3 of option 1 and 1 of option 2, got %d and %v", a.Items[0].Responses, a.Items[0].Options)
+		}
+	})
+
+	t.Run("Zero variance", func(t *testing.T) {
+		a := analyzeQuiz(quiz, answersOf(ids, []int{1, 1, 1}, []int{1, 1, 1}, []int{1, 1, 1}))
+		checkStat(t, "difficulty", a.Items[0].Difficulty, 1)
+		checkStat(t, "discrimination", a.Items[0].Discrimination, none)
+		checkStat(t, "KR-20", a.KR20, none)
+	})
+
+	t.Run("Single item", func(t *testing.T) {
+		single := Quiz{Id: 7, Questions: []Question{multiChoice(1)}}
+		a := analyzeQuiz(single, answersOf([]int{1}, []int{1}, []int{0}, []int{1}))
+		checkStat(t, "difficulty", a.Items[0].Difficulty, 2.0/3)
+		checkStat(t, "alpha", a.CronbachAlpha, none)
+		checkStat(t, "KR-20", a.KR20, none)
+	})
+
+	t.Run("Incomplete participations", func(t *testing.T) {
+		answers := answersOf(ids, []int{1, 1, 1}, []int{1, 1, 0}, []int{1, 0, 0}, []int{0, 0, 0})
+		delete(answers[4], 3)
+		a := analyzeQuiz(quiz, answers)
+		if a.Items[2].Responses != 3 {
+			t.Errorf("Want 3 responses, got %d", a.Items[2].Responses)
+		}
+		// Reliability only uses the three complete participations: item
+		// variances sum to 4/9 and total scores 3, 2, 1 vary by 2/3.
+		checkStat(t, "KR-20", a.KR20, 0.5)
+	})
+
+	t.Run("No participations", func(t *testing.T) {
+		a := analyzeQuiz(quiz, map[int]map[int]ParticipationAnswer{})
+		if a.Participations != 0 || len(a.Items) != 3 {
+			t.Fatalf("Want 0 participations and 3 items, got %d and %d", a.Participations, len(a.Items))
+		}
+		checkStat(t, "difficulty", a.Items[0].Difficulty, none)
+		checkStat(t, "discrimination", a.Items[0].Discrimination, none)
+		checkStat(t, "alpha", a.CronbachAlpha, none)
+	})
+}
@@ -7,7 +7,6 @@ import (
 	"net/http"
 
 	_ "github.com/lib/pq"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func SignupHandler(w http.ResponseWriter, r *http.Request) error {
@@ -16,9 +15,8 @@ func SignupHandler(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	var newUser NewUser
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&newUser); err != nil {
-		return NewClientError(err, 400, "Bad request : invalid JSON.")
+	if err := decodeJSON(w, r, &newUser); err != nil {
+		return err
 	}
 
 	if err := newUser.validate(); err != nil {
@@ -31,6 +29,9 @@ func SignupHandler(w http.ResponseWriter, r *http.Request) error {
 
 		return NewServerError(err, 500, "Create user error")
 	}
+	if err := sendVerificationEmail(user); err != nil {
+		requestLogger(r).Error("error sending verification email", "user", user.Username, "cause", causes(err))
+	}
 
 	mp := map[string]interface{}{"message": fmt.Sprintf("User %s created.", user.Username)}
 	js, err := json.Marshal(mp)
@@ -44,26 +45,51 @@ func SignupHandler(w http.ResponseWriter, r *http.Request) error {
 }
 
 func LoginHandler(w http.ResponseWriter, r *http.Request) error {
-	decoder := json.NewDecoder(r.Body)
-	var userCred NewUser
-	if err := decoder.Decode(&userCred); err != nil {
-		return NewClientError(err, 400, "Bad request : invalid JSON.")
+	var userCred LoginCredentials
+	if err := decodeJSON(w, r, &userCred); err != nil {
+		return err
+	}
+
+	if err := allow(loginUserLimiter, userCred.key()); err != nil {
+		return err
 	}
 
-	hashedPass, err := getUserPass(userCred.Username)
+	user, err := userCred.lookup()
 	if err != nil {
+		if httpErr, ok := err.(*HTTPError); ok && httpErr.Status == http.StatusUnauthorized {
+			loginFailures.Inc("unknown_user")
+		}
 		return err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(hashedPass), []byte("SomeSaltHereMaybeThere"+userCred.Password)); err != nil {
-		return NewClientError(err, http.StatusUnauthorized, "Username and password doesn't match.")
+	lock, err := checkLoginLock(r, user.Username)
+	if err != nil {
+		return err
+	}
+	if err := checkUserPassword(user, userCred.Password); err != nil {
+		if httpErr, ok := err.(*HTTPError); ok && httpErr.Status == http.StatusUnauthorized {
+			loginFailures.Inc("password")
+			if err := recordLoginFailure(user.Username, clientIP(r)); err != nil {
+				requestLogger(r).Error("error recording failed login", "user", user.Username, "cause", causes(err))
+			}
+		}
+		return err
+	}
+	if lock.Failures > 0 {
+		if err := resetLoginFailures(user.Username, clientIP(r)); err != nil {
+			requestLogger(r).Error("error resetting failed logins", "user", user.Username, "cause", causes(err))
+		}
 	}
 
-	if err := sessions.Login(w, r, userCred.Username); err != nil {
+	if user.TOTPEnabled {
+		startTwoFactor(w, user)
+		return writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Two-factor code required.", "two_factor_required": true})
+	}
+	if err := sessions.Login(w, r, user.Username); err != nil {
 		return NewServerError(err, 500, "Sessions login error")
 	}
 
-	mp := map[string]interface{}{"message": "Login succesful.", "username": userCred.Username}
+	mp := map[string]interface{}{"message": "Login succesful.", "username": user.Username}
 	js, err := json.Marshal(mp)
 	if err != nil {
 		return NewServerError(err, 500, "Error while parsing response body")
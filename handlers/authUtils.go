@@ -1,24 +1,46 @@
 package handlers
 
 import (
+	"PamQ/config"
 	db "PamQ/database"
+	"PamQ/logging"
+	"PamQ/passwords"
+	"PamQ/sessions"
 	"database/sql"
-	"errors"
 	"net/http"
+	"net/mail"
 	"regexp"
+	"strings"
+	"time"
 	"unicode"
 
-	_ "github.com/lib/pq"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/lib/pq"
 )
 
 type User struct {
-	Username       string   `db:"username"`
-	Email          string   `db:"email"`
-	HashedPassword string   `db:"password"`
+	Username       string   `json:"username" db:"username"`
+	Email          string   `json:"email" db:"email"`
+	HashedPassword string   `json:"-" db:"password"`
+	EmailVerified  bool     `json:"email_verified" db:"email_verified"`
+	DisplayName    string   `json:"display_name" db:"display_name"`
+	IsAdmin        bool     `json:"is_admin" db:"is_admin"`
 	DateCreated    JSONTime `json:"date_created" db:"date_created"`
+
+	TOTPEnabled  bool   `json:"two_factor_enabled" db:"totp_enabled"`
+	TOTPSecret   string `json:"-" db:"totp_secret"`
+	TOTPLastStep int64  `json:"-" db:"totp_last_step"`
 }
 
+// Wrong passwords and second factors are counted per username and client IP,
+// so that someone guessing from elsewhere can't lock the owner out. After
+// maxLoginFailures in a row the pair is locked for loginLockout, then gets
+// maxLoginFailures new tries. loginUserLimiter still slows down guessing the
+// password of a username from many addresses.
+var (
+	maxLoginFailures = config.Int("LOGIN_MAX_FAILURES", 5)
+	loginLockout     = config.Duration("LOGIN_LOCKOUT", 15*time.Minute)
+)
+
 type NewUser struct {
 	Username        string `json:"username"`
 	Email           string `json:"email"`
@@ -43,28 +65,49 @@ func validatePassword(password string) bool {
 	return true
 }
 
+// validateEmail accepts a bare address, without a display name, whose domain
+// has at least two labels.
+func validateEmail(email string) bool {
+	if len(email) > 200 {
+		return false
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return false
+	}
+	domain := email[strings.LastIndex(email, "@")+1:]
+	return strings.Contains(domain, ".") && !strings.HasPrefix(domain, ".") && !strings.HasSuffix(domain, ".")
+}
+
 func (u *NewUser) validate() error {
+	var errs FieldErrors
+	u.Email = strings.ToLower(u.Email)
 	if matched, err := regexp.Match(`^[A-Za-z]+[A-Za-z0-9]*(?:[_.][A-Za-z0-9]+)*$`, []byte(u.Username)); err != nil || matched == false || len(u.Username) < 3 || len(u.Username) > 30 {
-		return errors.New("Please enter a valid username.")
+		errs.add(invalidField("username", "Please enter a valid username."))
 	}
 	if len(u.Email) == 0 {
-		return ErrorMissingField("Email")
+		errs.add(ErrorMissingField("email"))
+	} else if !validateEmail(u.Email) {
+		errs.add(invalidField("email", "Please enter a valid email."))
 	}
-	if matched, err := regexp.Match(`[\w.]+@\w+\.\w+`, []byte(u.Email)); err != nil || matched == false {
-		return errors.New("Please enter a valid email.")
-	}
-	if !validatePassword(u.Password) {
-		return errors.New("Please enter a valid password. (at least 8 characters, one digit and one letter)")
+	errs.add(validateNewPassword(u.Password, u.PasswordConfirm))
+	return errs.err()
+}
+
+func validateNewPassword(password, confirm string) error {
+	var errs FieldErrors
+	if !validatePassword(password) {
+		errs.add(invalidField("password", "Please enter a valid password. (at least 8 characters, one digit and one letter)"))
 	}
-	if u.Password != u.PasswordConfirm {
-		return errors.New("Passwords don't match.")
+	if password != confirm {
+		errs.add(invalidField("password_confirm", "Passwords don't match."))
 	}
-	return nil
+	return errs.err()
 }
 
 func (user *User) addToDb() error {
 	db := db.DB
-	if _, err := db.Query("INSERT INTO userinfo VALUES ($1,$2,$3)", user.Username, user.Email, user.HashedPassword); err != nil {
+	if _, err := db.Exec("INSERT INTO userinfo (username, email, password) VALUES ($1,LOWER($2),$3)", user.Username, user.Email, user.HashedPassword); err != nil {
 		return err
 	}
 	return nil
@@ -72,7 +115,7 @@ func (user *User) addToDb() error {
 }
 
 func (u *NewUser) createUser() (*User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("SomeSaltHereMaybeThere"+u.Password), 8)
+	hashedPassword, err := passwords.Default.Hash(u.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -80,21 +123,182 @@ func (u *NewUser) createUser() (*User, error) {
 	user := &User{
 		Username:       u.Username,
 		Email:          u.Email,
-		HashedPassword: string(hashedPassword)}
+		HashedPassword: hashedPassword}
 
 	err = user.addToDb()
 	return user, err
 }
 
-func getUserPass(username string) (string, error) {
-	var hashedPass string
+// LoginCredentials identifies a user by username or email. For backward
+// compatibility an email may also be given in the username field.
+type LoginCredentials struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// key identifies the account being logged into for rate limiting.
+func (c *LoginCredentials) key() string {
+	if len(c.Email) != 0 {
+		return strings.ToLower(c.Email)
+	}
+	return strings.ToLower(c.Username)
+}
+
+func (c *LoginCredentials) lookup() (*User, error) {
+	var user *User
+	var err error
+	switch {
+	case len(c.Email) != 0:
+		user, err = getUserByEmail(c.Email)
+	case strings.Contains(c.Username, "@"):
+		user, err = getUserByEmail(c.Username)
+	case len(c.Username) != 0:
+		user, err = getUserByUsername(c.Username)
+	default:
+		return nil, NewClientError(ErrorMissingField("username"), http.StatusBadRequest, "Invalid form data: username or email is required.")
+	}
+	if herr, ok := err.(*HTTPError); ok && herr.Status == http.StatusNotFound {
+		return nil, NewClientError(err, http.StatusUnauthorized, "Username not found.")
+	}
+	return user, err
+}
+
+// checkUserPassword verifies password against the stored hash of user and
+// upgrades the hash when it was made with outdated parameters.
+func checkUserPassword(user *User, password string) error {
+	if len(user.HashedPassword) == 0 {
+		return NewClientError(nil, http.StatusUnauthorized, "This account has no password, please sign in with single sign-on.")
+	}
+	match, needsRehash, err := passwords.Default.Verify(password, user.HashedPassword)
+	if err != nil {
+		return NewServerError(err, 500, "Error checking password")
+	}
+	if !match {
+		return NewClientError(nil, http.StatusUnauthorized, "Username and password doesn't match.")
+	}
+	if needsRehash {
+		// The check succeeds even if the stored hash can't be upgraded.
+		if hashedPass, err := passwords.Default.Hash(password); err != nil {
+			logging.Default.Error("error rehashing password", "user", user.Username, "cause", causes(err))
+		} else if err := setUserPass(user.Username, hashedPass); err != nil {
+			logging.Default.Error("error rehashing password", "user", user.Username, "cause", causes(err))
+		} else {
+			user.HashedPassword = hashedPass
+		}
+	}
+	return nil
+}
+
+// Accounts created with single sign-on have no password. They confirm
+// sensitive changes by having signed in within reauthWindow instead.
+var reauthWindow = config.Duration("REAUTH_WINDOW", 10*time.Minute)
+
+// confirmUser checks that the logged in user of r is really user, with their
+// password or, for accounts without one, a recent sign-in.
+func confirmUser(r *http.Request, user *User, password string) error {
+	if len(user.HashedPassword) != 0 {
+		return checkUserPassword(user, password)
+	}
+	s, ok := sessions.Current(r)
+	if !ok || time.Since(s.Created) > reauthWindow {
+		return NewClientError(nil, http.StatusUnauthorized, "Please sign in again with single sign-on to confirm it's you.")
+	}
+	return nil
+}
+
+func setUserPass(username, hashedPass string) error {
+	db := db.DB
+	if _, err := db.Exec(`UPDATE userinfo SET password=$2 WHERE username=$1`, username, hashedPass); err != nil {
+		return err
+	}
+	return nil
+}
+
+// getUser returns the user matching condition, which compares a column with
+// value as $1.
+func getUser(condition, value string) (*User, error) {
+	var user User
 	db := db.DB
-	row := db.QueryRow(`SELECT password FROM userinfo WHERE username=$1`, username)
-	err := row.Scan(&hashedPass)
+	row := db.QueryRow(`SELECT username, COALESCE(email, ''), COALESCE(password, ''), email_verified, COALESCE(display_name, ''), is_admin, date_created,
+		totp_enabled, COALESCE(totp_secret, ''), totp_last_step FROM userinfo WHERE `+condition, value)
+	err := row.Scan(&user.Username, &user.Email, &user.HashedPassword, &user.EmailVerified, &user.DisplayName, &user.IsAdmin, &user.DateCreated,
+		&user.TOTPEnabled, &user.TOTPSecret, &user.TOTPLastStep)
 	if err == sql.ErrNoRows {
-		return hashedPass, NewClientError(err, http.StatusUnauthorized, "Username not found.")
+		return nil, NewClientError(err, http.StatusNotFound, "User not found.")
 	} else if err != nil {
-		return hashedPass, err
+		return nil, NewServerError(err, 500, "Error fetching data from database")
 	}
-	return hashedPass, nil
+	return &user, nil
+}
+
+// loginLock holds the failed logins of a username from one client IP.
+type loginLock struct {
+	Failures    int
+	LockedUntil *time.Time
+}
+
+// remaining returns how much longer the lock lasts at now, or 0 once it has
+// expired.
+func (l *loginLock) remaining(now time.Time) time.Duration {
+	if l.LockedUntil == nil || !now.Before(*l.LockedUntil) {
+		return 0
+	}
+	return l.LockedUntil.Sub(now)
+}
+
+func getLoginLock(username, ip string) (*loginLock, error) {
+	var lock loginLock
+	var lockedUntil pq.NullTime
+	db := db.DB
+	err := db.QueryRow(`SELECT failures, locked_until FROM login_failure WHERE username=$1 AND ip=$2`, username, ip).Scan(&lock.Failures, &lockedUntil)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if lockedUntil.Valid {
+		lock.LockedUntil = &lockedUntil.Time
+	}
+	return &lock, nil
+}
+
+// recordLoginFailure counts a failed login of username from ip and locks
+// the pair once there were too many in a row.
+func recordLoginFailure(username, ip string) error {
+	db := db.DB
+	_, err := db.Exec(`INSERT INTO login_failure AS f (username, ip, failures, locked_until)
+		VALUES ($1, $2, CASE WHEN 1 >= $3 THEN 0 ELSE 1 END, CASE WHEN 1 >= $3 THEN NOW() + $4 * INTERVAL '1 second' END)
+		ON CONFLICT (username, ip) DO UPDATE SET
+		locked_until = CASE WHEN f.failures + 1 >= $3 THEN NOW() + $4 * INTERVAL '1 second' ELSE f.locked_until END,
+		failures = CASE WHEN f.failures + 1 >= $3 THEN 0 ELSE f.failures + 1 END`,
+		username, ip, maxLoginFailures, int(loginLockout.Seconds()))
+	return err
+}
+
+func resetLoginFailures(username, ip string) error {
+	db := db.DB
+	_, err := db.Exec(`DELETE FROM login_failure WHERE username=$1 AND ip=$2`, username, ip)
+	return err
+}
+
+// checkLoginLock returns the failed logins of username from the client of r,
+// or an error while they are locked out.
+func checkLoginLock(r *http.Request, username string) (*loginLock, error) {
+	lock, err := getLoginLock(username, clientIP(r))
+	if err != nil {
+		return nil, NewServerError(err, 500, "Error fetching data from database")
+	}
+	if remaining := lock.remaining(time.Now()); remaining > 0 {
+		loginFailures.Inc("locked")
+		return nil, NewTooManyRequestsError(remaining, "Too many failed logins, please try again later.")
+	}
+	return lock, nil
+}
+
+func getUserByUsername(username string) (*User, error) {
+	return getUser("username=$1", username)
+}
+
+// getUserByEmail ignores the case of email; emails are stored in lower case.
+func getUserByEmail(email string) (*User, error) {
+	return getUser("email=LOWER($1)", email)
 }
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoginLockExpires(t *testing.T) {
+	now := time.Date(2020, 5, 1, 12, 0, 0, 0, time.UTC)
+	lockedUntil := now.Add(loginLockout)
+	lock := &loginLock{LockedUntil: &lockedUntil}
+
+	if got := lock.remaining(now); got != loginLockout {
+		t.Errorf("Want the lock to last %v, got %v", loginLockout, got)
+	}
+	if got := lock.remaining(now.Add(loginLockout - time.Second)); got != time.Second {
+		t.Errorf("Want 1s left before the end of the lock, got %v", got)
+	}
+	for _, at := range []time.Time{lockedUntil, lockedUntil.Add(time.Minute)} {
+		if got := lock.remaining(at); got != 0 {
+			t.Errorf("Want the lock expired at %v, got %v left", at, got)
+		}
+	}
+	if got := (&loginLock{Failures: 3}).remaining(now); got != 0 {
+		t.Errorf("Want failures without a lock to not lock, got %v", got)
+	}
+}
+
+func TestLoginFailuresPerAddress(t *testing.T) {
+	d, restore := useStubDB(t, nil)
+	defer restore()
+
+	if err := recordLoginFailure("alice", "203.0.113.7"); err != nil {
+		t.Fatal(err)
+	}
+	recorded := d.ran("INSERT INTO login_failure")
+	if len(recorded) != 1 {
+		t.Fatalf("Want the failure recorded, got %v", d.statements)
+	}
+	args := recorded[0].args
+	if len(args) != 4 || args[0] != "alice" || args[1] != "203.0.113.7" {
+		t.Errorf("Want the failure counted for alice from 203.0.113.7, got %v", args)
+	}
+
+	r := httptest.NewRequest("POST", "/api/login", nil)
+	r.RemoteAddr = "198.51.100.2:1234"
+	lock, err := checkLoginLock(r, "alice")
+	if err != nil || lock.Failures != 0 {
+		t.Errorf("Want no failures from another address, got %v, %v", lock, err)
+	}
+	looked := d.ran("FROM login_failure")
+	if len(looked) != 1 || looked[0].args[1] != "198.51.100.2" {
+		t.Errorf("Want the lock looked up for the address of the request, got %v", looked)
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Responses are kept by clients and shared caches but revalidated before
+// each reuse, so an edited quiz is never served stale. Responses carrying
+// data of the logged in user may only be kept by their own client.
+const (
+	publicCacheControl  = "public, no-cache"
+	privateCacheControl = "private, no-cache"
+)
+
+// quizETag identifies a representation of a quiz. It starts with the quiz
+// version, which If-Match compares on edits. The view of a logged in user
+// also depends on their remaining participations.
+func quizETag(version int, available *int) string {
+	if available == nil {
+		return fmt.Sprintf(`"v%d"`, version)
+	}
+	return fmt.Sprintf(`"v%d-%d"`, version, *available)
+}
+
+// etagMatches reports whether etag is in header, a list of entity tags as
+// sent in If-None-Match. Like If-None-Match, it ignores weakness.
+func etagMatches(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified sets the ETag and, if not zero, Last-Modified of a response.
+// When the conditions of r show the client already has it, it responds with
+// 304 Not Modified and returns true.
+func notModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if header := r.Header.Get("If-None-Match"); len(header) != 0 {
+		if !etagMatches(header, etag) {
+			return false
+		}
+	} else if header := r.Header.Get("If-Modified-Since"); len(header) != 0 && !lastModified.IsZero() {
+		since, err := http.ParseTime(header)
+		if err != nil || lastModified.Truncate(time.Second).After(since) {
+			return false
+		}
+	} else {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// writeCachedJSON is writeJSON for cacheable 200 responses, validated by a
+// weak ETag of the body.
+func writeCachedJSON(w http.ResponseWriter, r *http.Request, cacheControl string, v interface{}) error {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return NewServerError(err, 500, "Error while parsing response body")
+	}
+	sum := sha256.Sum256(js)
+	w.Header().Set("Cache-Control", cacheControl)
+	if notModified(w, r, `W/"`+base64.RawURLEncoding.EncodeToString(sum[:16])+`"`, time.Time{}) {
+		return nil
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(js)
+	return nil
+}
+
+// ifMatchVersions returns the quiz versions listed in the If-Match header of
+// r, or nil if any version will do. Tags that aren't quiz ETags can't match.
+func ifMatchVersions(r *http.Request) ([]int64, error) {
+	header := r.Header.Get("If-Match")
+	if len(header) == 0 || strings.TrimSpace(header) == "*" {
+		return nil, nil
+	}
+
+	versions := []int64{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if !strings.HasPrefix(tag, `"v`) || !strings.HasSuffix(tag, `"`) {
+			continue
+		}
+		version := strings.SplitN(tag[2:len(tag)-1], "-", 2)[0]
+		if v, err := strconv.ParseInt(version, 10, 64); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	if len(versions) == 0 {
+		return nil, NewCodedError(nil, http.StatusPreconditionFailed, CodePreconditionFailed, "If-Match doesn't name a version of this quiz.")
+	}
+	return versions, nil
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	db "PamQ/database"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQuizETagViews(t *testing.T) {
+	available := 2
+	if public, private := quizETag(3, nil), quizETag(3, &available); public != `"v3"` || private != `"v3-2"` {
+		t.Errorf(`Want "v3" and "v3-2", got %s and %s`, public, private)
+	}
+	if etagMatches(quizETag(3, nil), quizETag(3, &available)) {
+		t.Error("Want the public and private views of a version told apart")
+	}
+}
+
+func TestETagMatchesIgnoresWeakness(t *testing.T) {
+	if !etagMatches(`W/"v1"`, `"v1"`) || !etagMatches(`"abc"`, `W/"abc"`) {
+		t.Error("Want tags matched regardless of weakness")
+	}
+	if etagMatches(`"v12"`, `"v1"`) {
+		t.Error("Want other versions not matched")
+	}
+}
+
+func TestETagMatchesList(t *testing.T) {
+	if !etagMatches(`"v1", "v2"`, `"v2"`) || !etagMatches(` "v1" ,"v2" `, `"v1"`) {
+		t.Error("Want any tag of the list matched")
+	}
+	if !etagMatches("*", `"v1"`) {
+		t.Error("Want * to match any tag")
+	}
+}
+
+func conditionalGet(headers map[string]string, lastModified time.Time) (*httptest.ResponseRecorder, bool) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/quiz/1", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	return rec, notModified(rec, req, `"v2"`, lastModified)
+}
+
+func TestNotModifiedIfNoneMatch(t *testing.T) {
+	rec, ok := conditionalGet(map[string]string{"If-None-Match": `"v2"`}, time.Time{})
+	if !ok || rec.Code != http.StatusNotModified || rec.Header().Get("ETag") != `"v2"` {
+		t.Errorf("Want 304 with the ETag for a matching tag, got %v, %d, %v", ok, rec.Code, rec.Header())
+	}
+	if rec, ok := conditionalGet(map[string]string{"If-None-Match": `"v1"`}, time.Time{}); ok || rec.Header().Get("ETag") != `"v2"` {
+		t.Errorf("Want the full response with the ETag for another tag, got %v, %v", ok, rec.Header())
+	}
+	if _, ok := conditionalGet(nil, time.Time{}); ok {
+		t.Error("Want unconditional requests answered in full")
+	}
+}
+
+func TestNotModifiedIfModifiedSince(t *testing.T) {
+	lastModified := time.Date(2020, 1, 2, 3, 4, 5, 500000000, time.UTC)
+	since := func(t time.Time) map[string]string {
+		return map[string]string{"If-Modified-Since": t.Format(http.TimeFormat)}
+	}
+
+	rec, ok := conditionalGet(since(lastModified), lastModified)
+	if !ok {
+		t.Error("Want a change within the second of the date not modified")
+	}
+	if got := rec.Header().Get("Last-Modified"); got != "Thu, 02 Jan 2020 03:04:05 GMT" {
+		t.Errorf("Want Last-Modified set, got '%s'", got)
+	}
+	if _, ok := conditionalGet(since(lastModified.Add(-time.Second)), lastModified); ok {
+		t.Error("Want a later change modified")
+	}
+	if _, ok := conditionalGet(map[string]string{"If-Modified-Since": "yesterday"}, lastModified); ok {
+		t.Error("Want an invalid date ignored")
+	}
+	if rec, ok := conditionalGet(since(lastModified), time.Time{}); ok || len(rec.Header().Get("Last-Modified")) != 0 {
+		t.Error("Want If-Modified-Since ignored without a modification time")
+	}
+
+	headers := since(lastModified.Add(time.Hour))
+	headers["If-None-Match"] = `"v1"`
+	if _, ok := conditionalGet(headers, lastModified); ok {
+		t.Error("Want If-None-Match to take precedence")
+	}
+}
+
+func ifMatch(header string) ([]int64, error) {
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/quiz/1", nil)
+	if len(header) != 0 {
+		req.Header.Set("If-Match", header)
+	}
+	return ifMatchVersions(req)
+}
+
+func TestIfMatchVersionsAnyVersion(t *testing.T) {
+	for _, header := range []string{"", "*", " * "} {
+		if versions, err := ifMatch(header); versions != nil || err != nil {
+			t.Errorf("%q: want any version, got %v, %v", header, versions, err)
+		}
+	}
+}
+
+func TestIfMatchVersionsOfViews(t *testing.T) {
+	versions, err := ifMatch(`"v3", W/"v4", "v5-1", "abc"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{3, 5}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("Want the versions of the strong quiz tags %v, got %v", want, versions)
+	}
+}
+
+func TestIfMatchVersionsNoQuizTag(t *testing.T) {
+	for _, header := range []string{`W/"v3"`, `"abc"`, `"vx"`} {
+		var httpErr *HTTPError
+		if _, err := ifMatch(header); !errors.As(err, &httpErr) || httpErr.Status != http.StatusPreconditionFailed || httpErr.Code != CodePreconditionFailed {
+			t.Errorf("%q: want a precondition failure, got %v", header, err)
+		}
+	}
+}
+
+func TestQuizUpdateVersionMismatch(t *testing.T) {
+	d, restore := useStubDB(t, nil)
+	defer restore()
+	tx, err := db.DB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	quiz := Quiz{Id: 7, Name: "Arithmetic", GradingType: WithNegetiveMark}
+	err = quiz.update(tx, []int64{3, 4})
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Status != http.StatusPreconditionFailed || httpErr.Code != CodePreconditionFailed {
+		t.Fatalf("Want a precondition failure, got %v", err)
+	}
+	updates := d.ran("UPDATE quiz")
+	if len(updates) != 1 {
+		t.Fatalf("Want one update, got %v", d.statements)
+	}
+	if args := updates[0].args; args[len(args)-1] != "{3,4}" {
+		t.Errorf("Want the versions as the last argument, got %v", args)
+	}
+	if len(d.ran("question")) != 0 {
+		t.Error("Want the questions left alone")
+	}
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+)
+
+func CategoriesHandler(w http.ResponseWriter, r *http.Request) error {
+	if r.Method == http.MethodGet {
+		categories, err := getCategoryTree()
+		if err != nil {
+			return err
+		}
+		return writeJSON(w, http.StatusOK, map[string]interface{}{"categories": categories})
+	}
+
+	if _, err := requireAdmin(r); err != nil {
+		return err
+	}
+	var c CategoryUpdate
+	if err := decodeJSON(w, r, &c); err != nil {
+		return err
+	}
+	if err := c.validate(true); err != nil {
+		return NewClientError(err, http.StatusBadRequest, fmt.Sprintf("Invalid form data: %s", err.Error()))
+	}
+	if c.parentValue() != nil {
+		if err := checkCategory("parent_id", c.ParentID); err != nil {
+			return err
+		}
+	}
+
+	id, err := createCategory(&c)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusCreated, map[string]interface{}{"message": "Category created.", "id": id})
+}
+
+func CategoryHandler(w http.ResponseWriter, r *http.Request) error {
+	id, err := getCategoryIdParam(r)
+	if err != nil {
+		return err
+	}
+	if _, err := requireAdmin(r); err != nil {
+		return err
+	}
+
+	if r.Method == http.MethodDelete {
+		if err := deleteCategory(id); err != nil {
+			return err
+		}
+		return writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Category deleted."})
+	}
+
+	var c CategoryUpdate
+	if err := decodeJSON(w, r, &c); err != nil {
+		return err
+	}
+	if err := c.validate(false); err != nil {
+		return NewClientError(err, http.StatusBadRequest, fmt.Sprintf("Invalid form data: %s", err.Error()))
+	}
+	if c.ParentID != nil && *c.ParentID == id {
+		return NewClientError(invalidField("parent_id", "A category can't be moved below itself."), http.StatusBadRequest, "Invalid form data: A category can't be moved below itself.")
+	}
+	if c.parentValue() != nil {
+		if err := checkCategory("parent_id", c.ParentID); err != nil {
+			return err
+		}
+	}
+
+	if err := updateCategory(id, &c); err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Category updated."})
+}
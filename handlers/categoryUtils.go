@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	db "PamQ/database"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// Category is a node of the category tree managed by admins.
+type Category struct {
+	ID          int         `json:"id"`
+	ParentID    *int        `json:"parent_id"`
+	Name        string      `json:"name"`
+	Children    []*Category `json:"children"`
+	DateCreated JSONTime    `json:"date_created"`
+}
+
+// CategoryUpdate creates or changes a category. On update nil fields are
+// left as they are, and a parent_id of 0 moves the category to the top.
+type CategoryUpdate struct {
+	Name     *string `json:"name"`
+	ParentID *int    `json:"parent_id"`
+}
+
+func (c *CategoryUpdate) validate(create bool) error {
+	if c.Name != nil {
+		*c.Name = strings.TrimSpace(*c.Name)
+	}
+	var errs FieldErrors
+	if create && (c.Name == nil || len(*c.Name) == 0) {
+		errs.add(ErrorMissingField("name"))
+	} else if c.Name != nil && (len(*c.Name) == 0 || len(*c.Name) > 100) {
+		errs.add(invalidField("name", "Please enter a name of 1 to 100 characters."))
+	}
+	if c.ParentID != nil && *c.ParentID < 0 {
+		errs.add(invalidField("parent_id", "Please enter a valid parent_id."))
+	}
+	return errs.err()
+}
+
+// parentValue turns a parent_id of 0 into NULL.
+func (c *CategoryUpdate) parentValue() interface{} {
+	if c.ParentID == nil || *c.ParentID == 0 {
+		return nil
+	}
+	return *c.ParentID
+}
+
+// requireAdmin returns the logged in user if they are an admin.
+func requireAdmin(r *http.Request) (*User, error) {
+	user, err := loggedInUser(r)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsAdmin {
+		return nil, NewClientError(nil, http.StatusForbidden, "Only admins can do this")
+	}
+	return user, nil
+}
+
+func getCategoryIdParam(r *http.Request) (int, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["categoryID"])
+	if err != nil {
+		return 0, NewClientError(err, http.StatusBadRequest, "Bad request : invalid category id.")
+	}
+	return id, nil
+}
+
+// getCategoryTree returns the top level categories with their descendants.
+func getCategoryTree() ([]*Category, error) {
+	db := db.DB
+	rows, err := db.Query(`SELECT id, parent_id, name, date_created FROM category ORDER BY LOWER(name), id`)
+	if err != nil {
+		return nil, NewServerError(err, 500, "Error fetching data from database")
+	}
+	defer rows.Close()
+
+	var all []*Category
+	byID := map[int]*Category{}
+	for rows.Next() {
+		c := &Category{Children: []*Category{}}
+		var parentID sql.NullInt64
+		if err := rows.Scan(&c.ID, &parentID, &c.Name, &c.DateCreated); err != nil {
+			return nil, NewServerError(err, 500, "Error fetching data from database")
+		}
+		if parentID.Valid {
+			id := int(parentID.Int64)
+			c.ParentID = &id
+		}
+		all = append(all, c)
+		byID[c.ID] = c
+	}
+
+	roots := []*Category{}
+	for _, c := range all {
+		if parent, ok := byID[intValue(c.ParentID)]; ok {
+			parent.Children = append(parent.Children, c)
+		} else {
+			roots = append(roots, c)
+		}
+	}
+	return roots, nil
+}
+
+func intValue(p *int) int {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
+
+func createCategory(c *CategoryUpdate) (int, error) {
+	var id int
+	db := db.DB
+	if err := db.QueryRow(`INSERT INTO category (name, parent_id) VALUES ($1, $2) RETURNING id`, *c.Name, c.parentValue()).Scan(&id); err != nil {
+		return 0, NewServerError(err, 500, "Category not saved in database")
+	}
+	return id, nil
+}
+
+// updateCategory applies c to the category, refusing to move a category
+// below itself.
+func updateCategory(id int, c *CategoryUpdate) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return NewServerError(err, 500, "Error starting database transaction")
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE category SET name=COALESCE($2, name) WHERE id=$1`, id, c.Name)
+	if err != nil {
+		return NewServerError(err, 500, "Category not saved in database")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return NewClientError(nil, http.StatusNotFound, "Category not found")
+	}
+
+	if c.ParentID != nil {
+		if *c.ParentID != 0 {
+			var cycle bool
+			err := tx.QueryRow(`WITH RECURSIVE sub AS (SELECT id FROM category WHERE id=$1
+					UNION SELECT c.id FROM category c JOIN sub ON c.parent_id = sub.id)
+				SELECT EXISTS (SELECT 1 FROM sub WHERE id=$2)`, id, *c.ParentID).Scan(&cycle)
+			if err != nil {
+				return NewServerError(err, 500, "Error fetching data from database")
+			}
+			if cycle {
+				return NewClientError(invalidField("parent_id", "A category can't be moved below itself."), http.StatusBadRequest, "Invalid form data: A category can't be moved below itself.")
+			}
+		}
+		if _, err := tx.Exec(`UPDATE category SET parent_id=$2 WHERE id=$1`, id, c.parentValue()); err != nil {
+			return NewServerError(err, 500, "Category not saved in database")
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return NewServerError(err, 500, "Category not saved in database")
+	}
+	return nil
+}
+
+// checkCategory reports a field error unless the category with the given id,
+// if any, exists.
+func checkCategory(field string, id *int) error {
+	if id == nil {
+		return nil
+	}
+	var exists bool
+	db := db.DB
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM category WHERE id=$1)`, *id).Scan(&exists); err != nil {
+		return NewServerError(err, 500, "Error fetching data from database")
+	}
+	if !exists {
+		return NewClientError(invalidField(field, "Please enter an existing category."), http.StatusBadRequest, "Invalid form data: Please enter an existing category.")
+	}
+	return nil
+}
+
+// deleteCategory deletes a category without subcategories. Its quizzes are
+// left without a category.
+func deleteCategory(id int) error {
+	db := db.DB
+	var hasChildren bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM category WHERE parent_id=$1)`, id).Scan(&hasChildren); err != nil {
+		return NewServerError(err, 500, "Error fetching data from database")
+	}
+	if hasChildren {
+		return NewClientError(nil, http.StatusConflict, "Category has subcategories")
+	}
+
+	res, err := db.Exec(`DELETE FROM category WHERE id=$1`, id)
+	if err != nil {
+		return NewServerError(err, 500, "Category not deleted from database")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return NewClientError(nil, http.StatusNotFound, "Category not found")
+	}
+	return nil
+}
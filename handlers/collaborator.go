@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	db "PamQ/database"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func CollaboratorsHandler(w http.ResponseWriter, r *http.Request) error {
+	quizID, err := getQuizIdParam(r)
+	if err != nil {
+		return err
+	}
+
+	if r.Method == http.MethodGet {
+		if _, err := requireQuizPermission(r, quizID, ViewResults); err != nil {
+			return err
+		}
+		collaborators, err := getCollaborators(quizID)
+		if err != nil {
+			return err
+		}
+		return writeJSON(w, http.StatusOK, map[string]interface{}{"collaborators": collaborators})
+	}
+
+	username, err := requireQuizPermission(r, quizID, ManageCollaborators)
+	if err != nil {
+		return err
+	}
+
+	var newCollaborator NewCollaborator
+	if err := decodeJSON(w, r, &newCollaborator); err != nil {
+		return err
+	}
+	if err := newCollaborator.validate(); err != nil {
+		return NewClientError(err, http.StatusBadRequest, fmt.Sprintf("Invalid form data: %s", err.Error()))
+	}
+	if newCollaborator.Username == username {
+		return NewClientError(nil, http.StatusBadRequest, "You are already the creator of this quiz")
+	}
+
+	db := db.DB
+	var exists bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM userinfo WHERE username=$1)`, newCollaborator.Username).Scan(&exists); err != nil {
+		return NewServerError(err, 500, "Error fetching data from database")
+	}
+	if !exists {
+		return NewClientError(nil, http.StatusNotFound, "User not found")
+	}
+
+	collaborator := Collaborator{
+		QuizID:   quizID,
+		Username: newCollaborator.Username,
+		Role:     newCollaborator.Role,
+		AddedBy:  username}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return NewServerError(err, 500, "Error starting database transaction")
+	}
+	defer tx.Rollback()
+
+	if err := collaborator.addToDB(tx); err != nil {
+		return NewServerError(err, 500, "Collaborator not saved in database")
+	}
+	detail := fmt.Sprintf("%s as %s", collaborator.Username, collaborator.Role)
+	if err := addAuditEntry(tx, quizID, username, "collaborator.add", detail); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return NewServerError(err, 500, "Collaborator not saved in database")
+	}
+
+	return writeJSON(w, http.StatusCreated, map[string]interface{}{"message": "Collaborator saved.", "username": collaborator.Username, "role": collaborator.Role})
+}
+
+func RemoveCollaboratorHandler(w http.ResponseWriter, r *http.Request) error {
+	quizID, err := getQuizIdParam(r)
+	if err != nil {
+		return err
+	}
+	collaboratorName := mux.Vars(r)["username"]
+
+	// Collaborators may always remove themselves from a quiz.
+	username, err := requireQuizPermission(r, quizID, 0)
+	if err != nil {
+		return err
+	}
+	if username != collaboratorName {
+		if _, err := requireQuizPermission(r, quizID, ManageCollaborators); err != nil {
+			return err
+		}
+	}
+
+	db := db.DB
+	tx, err := db.Begin()
+	if err != nil {
+		return NewServerError(err, 500, "Error starting database transaction")
+	}
+	defer tx.Rollback()
+
+	var role Role
+	err = tx.QueryRow(`DELETE FROM quiz_collaborator WHERE quiz_id=$1 AND username=$2 RETURNING role`, quizID, collaboratorName).Scan(&role)
+	if err == sql.ErrNoRows {
+		return NewClientError(err, http.StatusNotFound, "Collaborator not found")
+	} else if err != nil {
+		return NewServerError(err, 500, "Collaborator not removed from database")
+	}
+	detail := fmt.Sprintf("%s as %s", collaboratorName, role)
+	if err := addAuditEntry(tx, quizID, username, "collaborator.remove", detail); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return NewServerError(err, 500, "Collaborator not removed from database")
+	}
+
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Collaborator removed."})
+}
+
+func QuizAuditHandler(w http.ResponseWriter, r *http.Request) error {
+	quizID, err := getQuizIdParam(r)
+	if err != nil {
+		return err
+	}
+	if _, err := requireQuizPermission(r, quizID, EditQuiz); err != nil {
+		return err
+	}
+
+	entries, err := getAuditLog(quizID)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"audit": entries})
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	db "PamQ/database"
+	"PamQ/sessions"
+	"database/sql"
+	"net/http"
+	"regexp"
+)
+
+type Role int
+
+const (
+	Editor Role = iota + 1
+	Grader
+	Viewer
+)
+
+func (r Role) String() string {
+	l := [...]string{"editor", "grader", "viewer"}
+	if r >= Editor && r <= Viewer {
+		return l[r-1]
+	}
+	return "unknown"
+}
+
+// Permission is a set of actions a user may perform on a quiz.
+type Permission int
+
+const (
+	EditQuiz Permission = 1 << iota
+	GradeParticipations
+	ViewResults
+	ManageCollaborators
+)
+
+// allPermissions is held by the creator of a quiz.
+const allPermissions = EditQuiz | GradeParticipations | ViewResults | ManageCollaborators
+
+// permissions returns what a collaborator with role r may do.
+func (r Role) permissions() Permission {
+	switch r {
+	case Editor:
+		return EditQuiz | ViewResults
+	case Grader:
+		return GradeParticipations | ViewResults
+	case Viewer:
+		return ViewResults
+	}
+	return 0
+}
+
+// has reports whether p includes every permission of perm.
+func (p Permission) has(perm Permission) bool {
+	return p&perm == perm
+}
+
+type Collaborator struct {
+	QuizID      int      `json:"-" db:"quiz_id"`
+	Username    string   `json:"username" db:"username"`
+	Role        Role     `json:"role" db:"role"`
+	AddedBy     string   `json:"added_by" db:"added_by"`
+	DateCreated JSONTime `json:"date_created" db:"date_created"`
+}
+
+type NewCollaborator struct {
+	Username string `json:"username"`
+	Role     Role   `json:"role"`
+}
+
+type AuditEntry struct {
+	ID          int      `json:"id"`
+	QuizID      int      `json:"quiz_id" db:"quiz_id"`
+	Username    string   `json:"username" db:"username"`
+	Action      string   `json:"action" db:"action"`
+	Detail      string   `json:"detail" db:"detail"`
+	DateCreated JSONTime `json:"date_created" db:"date_created"`
+}
+
+func (c *NewCollaborator) validate() error {
+	var errs FieldErrors
+	if len(c.Username) == 0 {
+		errs.add(ErrorMissingField("username"))
+	} else if matched, err := regexp.Match(`^[A-Za-z]+[A-Za-z0-9]*(?:[_.][A-Za-z0-9]+)*$`, []byte(c.Username)); err != nil || !matched {
+		errs.add(invalidField("username", "Please enter a valid username."))
+	}
+	if c.Role < Editor || c.Role > Viewer {
+		errs.add(invalidField("role", "Please enter a valid role. (1 for editor, 2 for grader or 3 for viewer)"))
+	}
+	return errs.err()
+}
+
+func (c *Collaborator) addToDB(db execer) error {
+	_, err := db.Exec(`INSERT INTO quiz_collaborator (quiz_id, username, role, added_by) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (quiz_id, username) DO UPDATE SET role=EXCLUDED.role`, c.QuizID, c.Username, c.Role, c.AddedBy)
+	return err
+}
+
+func getCollaborators(quizID int) ([]Collaborator, error) {
+	db := db.DB
+	rows, err := db.Query(`SELECT quiz_id, username, role, COALESCE(added_by, ''), date_created FROM quiz_collaborator WHERE quiz_id=$1 ORDER BY date_created`, quizID)
+	if err != nil {
+		return nil, NewServerError(err, 500, "Error fetching data from database")
+	}
+	defer rows.Close()
+
+	collaborators := []Collaborator{}
+	for rows.Next() {
+		var c Collaborator
+		if err := rows.Scan(&c.QuizID, &c.Username, &c.Role, &c.AddedBy, &c.DateCreated); err != nil {
+			return nil, NewServerError(err, 500, "Error fetching data from database")
+		}
+		collaborators = append(collaborators, c)
+	}
+	return collaborators, nil
+}
+
+// quizPermissions returns what username may do on the quiz. The creator of
+// a quiz holds every permission.
+func quizPermissions(quizID int, username string) (Permission, error) {
+	db := db.DB
+	var creator string
+	var role sql.NullInt64
+	err := db.QueryRow(`SELECT q.creator, c.role FROM quiz q
+		LEFT JOIN quiz_collaborator c ON c.quiz_id = q.id AND c.username = $2
+		WHERE q.id=$1`, quizID, username).Scan(&creator, &role)
+	if err == sql.ErrNoRows {
+		return 0, NewClientError(err, http.StatusNotFound, "Quiz not found")
+	} else if err != nil {
+		return 0, NewServerError(err, 500, "Error fetching data from database")
+	}
+
+	if creator == username {
+		return allPermissions, nil
+	}
+	if role.Valid {
+		return Role(role.Int64).permissions(), nil
+	}
+	return 0, nil
+}
+
+// requireQuizPermission checks that the logged in user holds perm on the quiz
+// and returns their username.
+func requireQuizPermission(r *http.Request, quizID int, perm Permission) (string, error) {
+	if !sessions.IsLoggedIn(r) {
+		return "", NewClientError(nil, http.StatusUnauthorized, "Please login first")
+	}
+	username, ok := sessions.GetUsername(r)
+	if !ok {
+		return "", NewServerError(nil, 500, "Error getting username from session")
+	}
+
+	perms, err := quizPermissions(quizID, username)
+	if err != nil {
+		return "", err
+	}
+	if !perms.has(perm) {
+		return "", NewClientError(nil, http.StatusForbidden, "You don't have permission to do this on this quiz")
+	}
+	return username, nil
+}
+
+func addAuditEntry(db execer, quizID int, username, action, detail string) error {
+	if _, err := db.Exec(`INSERT INTO quiz_audit (quiz_id, username, action, detail) VALUES ($1, $2, $3, $4)`, quizID, username, action, detail); err != nil {
+		return NewServerError(err, 500, "Audit entry not saved in database")
+	}
+	return nil
+}
+
+func getAuditLog(quizID int) ([]AuditEntry, error) {
+	db := db.DB
+	rows, err := db.Query(`SELECT id, quiz_id, COALESCE(username, ''), action, COALESCE(detail, ''), date_created FROM quiz_audit WHERE quiz_id=$1 ORDER BY id DESC`, quizID)
+	if err != nil {
+		return nil, NewServerError(err, 500, "Error fetching data from database")
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.QuizID, &e.Username, &e.Action, &e.Detail, &e.DateCreated); err != nil {
+			return nil, NewServerError(err, 500, "Error fetching data from database")
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
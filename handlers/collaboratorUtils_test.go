@@ -0,0 +1,32 @@
+package handlers
+
+import "testing"
+
+func TestRolePermissions(t *testing.T) {
+	want := map[Role]Permission{
+		Editor: EditQuiz | ViewResults,
+		Grader: GradeParticipations | ViewResults,
+		Viewer: ViewResults,
+	}
+	for role, perms := range want {
+		if got := role.permissions(); got != perms {
+			t.Errorf("%s: want permissions %b, got %b", role, perms, got)
+		}
+		if !allPermissions.has(role.permissions()) {
+			t.Errorf("%s: the creator must hold every permission of a collaborator", role)
+		}
+		if role.permissions().has(ManageCollaborators) {
+			t.Errorf("%s: only the creator may manage collaborators", role)
+		}
+	}
+
+	if perms := Role(9).permissions(); perms != 0 {
+		t.Errorf("An unknown role must grant nothing, got %b", perms)
+	}
+	if (EditQuiz | ViewResults).has(EditQuiz | GradeParticipations) {
+		t.Error("Combined permissions must all be held")
+	}
+	if !Viewer.permissions().has(0) {
+		t.Error("Every role must allow what needs no permission")
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"PamQ/config"
+	"PamQ/sessions"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Cookie authenticated requests that change state must echo the value of the
+// CSRF cookie in the CSRF header. A cross-site page can make the browser send
+// the cookie but can't read it to set the header.
+const (
+	CSRFCookieName = "csrf_token"
+	CSRFHeaderName = "X-CSRF-Token"
+)
+
+// csrfTrustedOrigins lists origins, besides the one of the API itself, that
+// may send state-changing requests, e.g. a front-end served from elsewhere.
+var csrfTrustedOrigins = config.String("CSRF_TRUSTED_ORIGINS", "")
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func newCSRFToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func setCSRFCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     CSRFCookieName,
+		Value:    token,
+		Path:     "/",
+		Secure:   sessions.SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// trustedOrigin reports whether the Origin (or, failing that, Referer) of r
+// is the API itself or one of csrfTrustedOrigins. Requests without either
+// header are left to the token check.
+func trustedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if len(origin) == 0 {
+		referer, err := url.Parse(r.Header.Get("Referer"))
+		if err != nil || len(referer.Host) == 0 {
+			return true
+		}
+		origin = referer.Scheme + "://" + referer.Host
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, trusted := range strings.Split(csrfTrustedOrigins, ",") {
+		if strings.EqualFold(strings.TrimSpace(trusted), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// CSRFProtect rejects state-changing requests that don't carry a valid CSRF
+// token. Requests made with an API token aren't sent by browsers on their
+// own, so they are exempt; BearerAuth must run before it.
+func CSRFProtect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isSafeMethod(r.Method) || sessions.IsTokenRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !trustedOrigin(r) {
+			reject(w, r, NewCodedError(nil, http.StatusForbidden, CodeCSRF, "Cross-origin request rejected"))
+			return
+		}
+		cookie, err := r.Cookie(CSRFCookieName)
+		if err != nil || len(cookie.Value) == 0 {
+			reject(w, r, NewCodedError(err, http.StatusForbidden, CodeCSRF, "Missing CSRF token. Please get one from /api/v1/csrf"))
+			return
+		}
+		header := r.Header.Get(CSRFHeaderName)
+		if subtle.ConstantTimeCompare([]byte(header), []byte(cookie.Value)) != 1 {
+			reject(w, r, NewCodedError(nil, http.StatusForbidden, CodeCSRF, "Invalid CSRF token"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CSRFTokenHandler returns the CSRF token to send in the CSRF header, setting
+// the cookie first if the client doesn't have one yet.
+func CSRFTokenHandler(w http.ResponseWriter, r *http.Request) error {
+	token := ""
+	if cookie, err := r.Cookie(CSRFCookieName); err == nil {
+		token = cookie.Value
+	}
+	if len(token) == 0 {
+		var err error
+		if token, err = newCSRFToken(); err != nil {
+			return NewServerError(err, 500, "Error generating CSRF token")
+		}
+		setCSRFCookie(w, token)
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"csrf_token": token, "header": CSRFHeaderName})
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	db "PamQ/database"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// stubDriver records the statements run. Queries containing a key of
+// results return its rows, others no rows, as Postgres does for an
+// UPDATE ... RETURNING matching nothing.
+type stubDriver struct {
+	mu         sync.Mutex
+	statements []stubStatement
+	results    map[string][][]driver.Value
+}
+
+type stubStatement struct {
+	query string
+	args  []driver.Value
+}
+
+func (d *stubDriver) Open(string) (driver.Conn, error) { return stubConn{d}, nil }
+
+func (d *stubDriver) record(query string, args []driver.Value) [][]driver.Value {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.statements = append(d.statements, stubStatement{query, args})
+	for fragment, rows := range d.results {
+		if strings.Contains(query, fragment) {
+			return rows
+		}
+	}
+	return nil
+}
+
+// ran returns the recorded statements containing fragment.
+func (d *stubDriver) ran(fragment string) []stubStatement {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var found []stubStatement
+	for _, s := range d.statements {
+		if strings.Contains(s.query, fragment) {
+			found = append(found, s)
+		}
+	}
+	return found
+}
+
+type stubConn struct{ d *stubDriver }
+
+func (c stubConn) Prepare(query string) (driver.Stmt, error) { return stubStmt{c.d, query}, nil }
+func (c stubConn) Close() error                              { return nil }
+func (c stubConn) Begin() (driver.Tx, error)                 { return stubTx{}, nil }
+
+type stubStmt struct {
+	d     *stubDriver
+	query string
+}
+
+func (s stubStmt) Close() error  { return nil }
+func (s stubStmt) NumInput() int { return -1 }
+func (s stubStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.record(s.query, args)
+	return driver.RowsAffected(0), nil
+}
+func (s stubStmt) Query(args []driver.Value) (driver.Rows, error) {
+	rows := &stubRows{rows: s.d.record(s.query, args)}
+	if len(rows.rows) != 0 {
+		rows.columns = make([]string, len(rows.rows[0]))
+	}
+	return rows, nil
+}
+
+type stubRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *stubRows) Columns() []string { return r.columns }
+func (r *stubRows) Close() error      { return nil }
+func (r *stubRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+type stubTx struct{}
+
+func (stubTx) Commit() error   { return nil }
+func (stubTx) Rollback() error { return nil }
+
+var stubDBs int
+
+// useStubDB replaces the database with a stub answering queries with results
+// until the returned function is called.
+func useStubDB(t *testing.T, results map[string][][]driver.Value) (*stubDriver, func()) {
+	t.Helper()
+	d := &stubDriver{results: results}
+	stubDBs++
+	name := fmt.Sprintf("stub-%d", stubDBs)
+	sql.Register(name, d)
+	conn, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := db.DB
+	db.DB = conn
+	return d, func() {
+		db.DB = old
+		conn.Close()
+	}
+}
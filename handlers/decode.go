@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"PamQ/config"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxBodySize limits the size of JSON request bodies. Quizzes with many
+// questions are the largest.
+var maxBodySize = int64(config.Int("MAX_BODY_BYTES", 1<<20))
+
+// decodeJSON reads the JSON body of r into v. Bodies larger than maxBodySize,
+// fields v doesn't have and data after the JSON value are rejected.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodySize)
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return decodeError(err)
+	}
+	if err := decoder.Decode(&json.RawMessage{}); err != io.EOF {
+		if err == nil {
+			err = errors.New("data after the JSON value")
+		}
+		return decodeError(err)
+	}
+	return nil
+}
+
+// decodeStrict is json.Unmarshal rejecting unknown fields. Types with their
+// own UnmarshalJSON use it, as the decoder's setting doesn't reach them.
+func decodeStrict(b []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return jsonFieldError(err)
+	}
+	return nil
+}
+
+// decodeError turns an error of decodeJSON into the client error to return,
+// naming the field at fault when there is one.
+func decodeError(err error) error {
+	// http.MaxBytesReader doesn't export its error.
+	if strings.Contains(err.Error(), "request body too large") {
+		return NewCodedError(err, http.StatusRequestEntityTooLarge, CodePayloadTooLarge,
+			fmt.Sprintf("Request body is larger than %d bytes.", maxBodySize))
+	}
+
+	err = jsonFieldError(err)
+	if fieldErrors(err) != nil {
+		return NewClientError(err, http.StatusBadRequest, fmt.Sprintf("Invalid form data: %s", err.Error()))
+	}
+	return NewInvalidJSONError(err)
+}
+
+// jsonFieldError returns the type and unknown field errors of encoding/json
+// as FieldErrors, and other errors as they are.
+func jsonFieldError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && len(typeErr.Field) != 0 {
+		return FieldError{Field: typeErr.Field, Code: "invalid_type", Message: fmt.Sprintf("%s must be %s.", typeErr.Field, jsonTypeName(typeErr.Type.Kind().String()))}
+	}
+	if name := strings.TrimPrefix(err.Error(), "json: unknown field "); name != err.Error() {
+		if field, uerr := strconv.Unquote(name); uerr == nil {
+			return FieldError{Field: field, Code: "unknown", Message: fmt.Sprintf("%s is not a known field.", field)}
+		}
+	}
+	return err
+}
+
+// jsonTypeName describes the JSON value expected for a Go kind.
+func jsonTypeName(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"):
+		return "an integer"
+	case strings.HasPrefix(kind, "float"):
+		return "a number"
+	case kind == "bool":
+		return "true or false"
+	case kind == "string":
+		return "a string"
+	case kind == "slice", kind == "array":
+		return "an array"
+	}
+	return "an object"
+}
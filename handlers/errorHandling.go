@@ -1,8 +1,14 @@
 package handlers
 
 import (
-	"encoding/json"
-	"fmt"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 type ErrorMissingField string
@@ -11,11 +17,121 @@ func (e ErrorMissingField) Error() string {
 	return string(e) + " is required."
 }
 
-// type ClientError interface {
-// 	Error() string
-// 	ResponseBody() ([]byte, error)
-// 	ResponseHeaders() (int, map[string]string)
-// }
+// FieldError reports one invalid field of a request. Validation errors
+// returned as the cause of a 400 are listed in the "errors" of the response.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+func invalidField(field, message string) error {
+	return FieldError{Field: field, Code: "invalid", Message: message}
+}
+
+// FieldErrors lists every invalid field of a request, so that a client can
+// fix them all at once.
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Message
+	}
+	return strings.Join(messages, " ")
+}
+
+// add records the field errors of err, if any.
+func (e *FieldErrors) add(err error) {
+	*e = append(*e, fieldErrors(err)...)
+}
+
+// err returns the recorded errors, or nil if there are none.
+func (e FieldErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// fieldErrors returns the field errors found in the chain of err.
+func fieldErrors(err error) []FieldError {
+	var list FieldErrors
+	if errors.As(err, &list) {
+		return list
+	}
+	var fieldErr FieldError
+	if errors.As(err, &fieldErr) {
+		return []FieldError{fieldErr}
+	}
+	var missing ErrorMissingField
+	if errors.As(err, &missing) {
+		return []FieldError{{Field: string(missing), Code: "required", Message: missing.Error()}}
+	}
+	return nil
+}
+
+// prefixField places the fields err is about under prefix, so that errors of
+// nested values name their path: "option2" becomes "questions[3].option2".
+func prefixField(prefix string, err error) error {
+	list := fieldErrors(jsonFieldError(err))
+	if list == nil {
+		return err
+	}
+	prefixed := make(FieldErrors, len(list))
+	for i, fieldErr := range list {
+		path := prefix + "." + fieldErr.Field
+		if strings.HasPrefix(fieldErr.Message, fieldErr.Field+" ") {
+			fieldErr.Message = path + strings.TrimPrefix(fieldErr.Message, fieldErr.Field)
+		}
+		fieldErr.Field = path
+		prefixed[i] = fieldErr
+	}
+	if len(prefixed) == 1 {
+		if prefixed[0].Code == "required" {
+			return ErrorMissingField(prefixed[0].Field)
+		}
+		return prefixed[0]
+	}
+	return prefixed
+}
+
+// Error codes are part of the API: clients may rely on them, so existing
+// codes must not change meaning.
+const (
+	CodeBadRequest           = "bad_request"
+	CodeInvalidJSON          = "invalid_json"
+	CodeValidation           = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeConflict             = "conflict"
+	CodeUnprocessable        = "unprocessable_entity"
+	CodeRateLimited          = "rate_limited"
+	CodePreconditionFailed   = "precondition_failed"
+	CodeIdempotencyKeyReused = "idempotency_key_reused"
+	CodePayloadTooLarge      = "payload_too_large"
+	CodeCSRF                 = "csrf_failed"
+	CodeInternal             = "internal_error"
+	CodeUpstream             = "upstream_error"
+)
+
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            CodeBadRequest,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusConflict:              CodeConflict,
+	http.StatusUnprocessableEntity:   CodeUnprocessable,
+	http.StatusPreconditionFailed:    CodePreconditionFailed,
+	http.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+	http.StatusTooManyRequests:       CodeRateLimited,
+	http.StatusBadGateway:            CodeUpstream,
+}
 
 type ErrorType int
 
@@ -25,10 +141,12 @@ const (
 )
 
 type HTTPError struct {
-	Type   ErrorType `json:"-"`
-	Cause  error     `json:"-"`
-	Detail string    `json:"detail"`
-	Status int       `json:"-"`
+	Type    ErrorType
+	Cause   error
+	Code    string
+	Detail  string
+	Status  int
+	Headers map[string]string
 }
 
 func (e HTTPError) Error() string {
@@ -38,17 +156,59 @@ func (e HTTPError) Error() string {
 	return e.Detail + ": " + e.Cause.Error()
 }
 
-func (e *HTTPError) ResponseBody() ([]byte, error) {
-	js, err := json.Marshal(e)
-	if err != nil {
-		return nil, fmt.Errorf("Error while parsing response body: %v", err)
+func (e *HTTPError) Unwrap() error {
+	return e.Cause
+}
+
+// Problem is an RFC 7807 problem details object. Only Detail and the field
+// errors come from the handler; the cause of an error is never sent.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Code     string       `json:"code"`
+	Detail   string       `json:"detail"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+func (e *HTTPError) code() string {
+	if len(e.Code) != 0 {
+		return e.Code
+	}
+	if e.Status == http.StatusBadRequest && fieldErrors(e.Cause) != nil {
+		return CodeValidation
+	}
+	if code, ok := statusCodes[e.Status]; ok {
+		return code
+	}
+	if e.Status >= 500 {
+		return CodeInternal
+	}
+	return CodeBadRequest
+}
+
+func (e *HTTPError) Problem() Problem {
+	code := e.code()
+	p := Problem{
+		Type:   "urn:pamq:error:" + code,
+		Title:  http.StatusText(e.Status),
+		Status: e.Status,
+		Code:   code,
+		Detail: e.Detail,
 	}
-	return js, nil
+	if e.Type == ClientError {
+		p.Errors = fieldErrors(e.Cause)
+	}
+	return p
 }
 
 func (e *HTTPError) ResponseHeaders() (int, map[string]string) {
 	mp := map[string]string{
-		"Content-Type": "application/json; charset=utf-8",
+		"Content-Type": "application/problem+json; charset=utf-8",
+	}
+	for k, v := range e.Headers {
+		mp[k] = v
 	}
 	return e.Status, mp
 }
@@ -63,10 +223,71 @@ func NewClientError(err error, status int, detail string) error {
 }
 
 func NewServerError(err error, status int, detail string) error {
+	return &HTTPError{
+		Type:   ServerError,
+		Cause:  err,
+		Detail: detail,
+		Status: status,
+	}
+}
+
+// NewCodedError is a client error with a code more specific than the one
+// implied by its status.
+func NewCodedError(err error, status int, code, detail string) error {
 	return &HTTPError{
 		Type:   ClientError,
 		Cause:  err,
+		Code:   code,
 		Detail: detail,
 		Status: status,
 	}
 }
+
+func NewInvalidJSONError(err error) error {
+	return NewCodedError(err, http.StatusBadRequest, CodeInvalidJSON, "Bad request : invalid JSON.")
+}
+
+// NewTooManyRequestsError asks the client to wait for retryAfter, rounded up
+// to the second, before trying again.
+func NewTooManyRequestsError(retryAfter time.Duration, detail string) error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return &HTTPError{
+		Type:    ClientError,
+		Detail:  detail,
+		Status:  http.StatusTooManyRequests,
+		Headers: map[string]string{"Retry-After": strconv.Itoa(seconds)},
+	}
+}
+
+// toHTTPError turns any error returned by a handler into the HTTPError to
+// respond with. Constraint violations reported by the database are the
+// client's fault; anything else unexpected is an internal error.
+func toHTTPError(err error) *HTTPError {
+	var httpError *HTTPError
+	if !errors.As(err, &httpError) {
+		httpError = &HTTPError{Type: ServerError, Cause: err, Status: http.StatusInternalServerError}
+	}
+	if httpError.Type == ClientError {
+		return httpError
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			return &HTTPError{Type: ClientError, Cause: err, Status: http.StatusConflict, Detail: "This already exists."}
+		case "foreign_key_violation":
+			return &HTTPError{Type: ClientError, Cause: err, Status: http.StatusUnprocessableEntity, Detail: "A referenced item doesn't exist."}
+		case "not_null_violation", "check_violation", "string_data_right_truncation":
+			return &HTTPError{Type: ClientError, Cause: err, Status: http.StatusUnprocessableEntity, Detail: "Invalid data."}
+		}
+	}
+
+	if len(httpError.Detail) == 0 {
+		httpError.Detail = "Something went wrong, please try again later."
+	}
+	return httpError
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	db "PamQ/database"
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const readinessTimeout = 2 * time.Second
+
+var draining int32
+
+// StartDraining makes the server report itself as not ready, so load
+// balancers stop sending it requests while it shuts down.
+func StartDraining() {
+	atomic.StoreInt32(&draining, 1)
+}
+
+// HealthzHandler reports that the process is alive. It doesn't check any
+// dependency, so a database outage doesn't get the server restarted.
+func HealthzHandler(w http.ResponseWriter, r *http.Request) error {
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"status": "ok"})
+}
+
+// ReadyzHandler reports whether the server can serve requests: it isn't
+// shutting down, the database answers and its schema is current.
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Cache-Control", "no-store")
+	if atomic.LoadInt32(&draining) == 1 {
+		return writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"status": "shutting down"})
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	checks := map[string]string{"database": "ok", "schema": "ok"}
+	status := http.StatusOK
+	if err := db.DB.PingContext(ctx); err != nil {
+		requestLogger(r).Warn("readiness check failed", "check", "database", "cause", causes(err))
+		checks["database"], checks["schema"] = "unavailable", "unknown"
+		status = http.StatusServiceUnavailable
+	} else if err := db.CheckSchema(ctx); err != nil {
+		requestLogger(r).Warn("readiness check failed", "check", "schema", "cause", causes(err))
+		checks["schema"] = "outdated"
+		status = http.StatusServiceUnavailable
+	}
+
+	result := "ok"
+	if status != http.StatusOK {
+		result = "unavailable"
+	}
+	return writeJSON(w, status, map[string]interface{}{"status": result, "checks": checks})
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"PamQ/config"
+	db "PamQ/database"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	IdempotencyKeyHeader    = "Idempotency-Key"
+	idempotentReplayHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength = 255
+)
+
+var (
+	// Keys are forgotten after idempotencyKeyTTL, so clients must not retry
+	// with the same key for longer than that.
+	idempotencyKeyTTL = config.Duration("IDEMPOTENCY_KEY_TTL", 24*time.Hour)
+	// A key still without a response after idempotencyInProgressTimeout was
+	// left by a request that never finished, and is given to the next one.
+	idempotencyInProgressTimeout = config.Duration("IDEMPOTENCY_IN_PROGRESS_TIMEOUT", time.Minute)
+)
+
+// storedResponse is the response to the first request sent with a key.
+type storedResponse struct {
+	status int
+	body   []byte
+}
+
+// replay writes the stored response, marked as replayed.
+func (s *storedResponse) replay(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(idempotentReplayHeader, "true")
+	w.WriteHeader(s.status)
+	w.Write(s.body)
+}
+
+// idempotencyKey returns the Idempotency-Key of r, if any.
+func idempotencyKey(r *http.Request) (string, error) {
+	key := r.Header.Get(IdempotencyKeyHeader)
+	if len(key) > maxIdempotencyKeyLength {
+		return "", NewClientError(nil, http.StatusBadRequest, "Idempotency-Key must be at most "+strconv.Itoa(maxIdempotencyKeyLength)+" characters.")
+	}
+	for _, c := range key {
+		if c < ' ' || c > '~' {
+			return "", NewClientError(nil, http.StatusBadRequest, "Idempotency-Key must be printable ASCII.")
+		}
+	}
+	return key, nil
+}
+
+// requestHash identifies what a request asks for, so that a key reused for
+// another request is noticed.
+func requestHash(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(strconv.Itoa(len(p)) + ":" + p))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// idempotencyRecord is what is stored for a key. Status is NULL until the
+// request that reserved the key has recorded its response.
+type idempotencyRecord struct {
+	RequestHash string
+	Status      sql.NullInt64
+	Response    []byte
+	DateCreated time.Time
+}
+
+// resolve decides what a request with the given hash, sent at now, does with
+// a key already recorded as rec: it gets the stored response or is refused.
+// When the request that reserved the key was abandoned, both results are nil
+// and the key may be taken over.
+func (rec *idempotencyRecord) resolve(hash string, now time.Time) (*storedResponse, error) {
+	if rec.RequestHash != hash {
+		return nil, NewCodedError(nil, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused, "This Idempotency-Key was used for another request.")
+	}
+	if !rec.Status.Valid {
+		if now.Sub(rec.DateCreated) > idempotencyInProgressTimeout {
+			return nil, nil
+		}
+		return nil, NewClientError(nil, http.StatusConflict, "A request with this Idempotency-Key is still being processed.")
+	}
+	return &storedResponse{status: int(rec.Status.Int64), body: rec.Response}, nil
+}
+
+// reserveIdempotencyKey claims key for a request of username. If the key
+// was already used for the same request, its stored response is returned
+// instead and the request must not be processed again.
+func reserveIdempotencyKey(username, key, hash string) (*storedResponse, error) {
+	db := db.DB
+	now := time.Now()
+	if _, err := db.Exec(`DELETE FROM idempotency_key WHERE username=$1 AND date_created < $2`, username, now.Add(-idempotencyKeyTTL)); err != nil {
+		return nil, NewServerError(err, 500, "Error deleting expired idempotency keys")
+	}
+
+	res, err := db.Exec(`INSERT INTO idempotency_key (username, key, request_hash) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`, username, key, hash)
+	if err != nil {
+		return nil, NewServerError(err, 500, "Error saving idempotency key")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 1 {
+		return nil, nil
+	}
+
+	var rec idempotencyRecord
+	err = db.QueryRow(`SELECT request_hash, status, response, date_created FROM idempotency_key WHERE username=$1 AND key=$2`, username, key).Scan(&rec.RequestHash, &rec.Status, &rec.Response, &rec.DateCreated)
+	if err != nil {
+		return nil, NewServerError(err, 500, "Error fetching data from database")
+	}
+	stored, err := rec.resolve(hash, now)
+	if stored != nil || err != nil {
+		return stored, err
+	}
+
+	// Only one of the requests retrying an abandoned key takes it over.
+	res, err = db.Exec(`UPDATE idempotency_key SET date_created=NOW() WHERE username=$1 AND key=$2 AND status IS NULL AND date_created < $3`, username, key, now.Add(-idempotencyInProgressTimeout))
+	if err != nil {
+		return nil, NewServerError(err, 500, "Error saving idempotency key")
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return nil, NewClientError(err, http.StatusConflict, "A request with this Idempotency-Key is still being processed.")
+	}
+	return nil, nil
+}
+
+// saveIdempotentResponse stores the response to replay for key. It runs in
+// the transaction recording the effects of the request, so that they are
+// never saved without it.
+func saveIdempotentResponse(db execer, username, key string, status int, body []byte) error {
+	if _, err := db.Exec(`UPDATE idempotency_key SET status=$3, response=$4 WHERE username=$1 AND key=$2`, username, key, status, body); err != nil {
+		return NewServerError(err, 500, "Error saving idempotent response")
+	}
+	return nil
+}
+
+// releaseIdempotencyKey frees key after a request that changed nothing
+// failed, so that it can be retried.
+func releaseIdempotencyKey(username, key string) error {
+	db := db.DB
+	if _, err := db.Exec(`DELETE FROM idempotency_key WHERE username=$1 AND key=$2 AND status IS NULL`, username, key); err != nil {
+		return NewServerError(err, 500, "Error deleting idempotency key")
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func requestWithKey(key string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/quiz/1", nil)
+	req.Header[IdempotencyKeyHeader] = []string{key}
+	return req
+}
+
+func wantStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Status != status {
+		t.Errorf("Want %d, got %v", status, err)
+	}
+}
+
+func TestIdempotencyKeyOptional(t *testing.T) {
+	key, err := idempotencyKey(httptest.NewRequest(http.MethodPost, "/api/v1/quiz/1", nil))
+	if key != "" || err != nil {
+		t.Errorf("Want no key, got '%s', %v", key, err)
+	}
+}
+
+func TestIdempotencyKeyLength(t *testing.T) {
+	longest := strings.Repeat("k", maxIdempotencyKeyLength)
+	if key, err := idempotencyKey(requestWithKey(longest)); key != longest || err != nil {
+		t.Errorf("Want a key of %d characters accepted, got %v", maxIdempotencyKeyLength, err)
+	}
+	_, err := idempotencyKey(requestWithKey(longest + "k"))
+	wantStatus(t, err, http.StatusBadRequest)
+}
+
+func TestIdempotencyKeyCharacters(t *testing.T) {
+	uuid := "8f14e45f-ceea-467f-a0e6-6e1f3c4b8a2d"
+	if key, err := idempotencyKey(requestWithKey(uuid)); key != uuid || err != nil {
+		t.Errorf("Want a UUID accepted, got '%s', %v", key, err)
+	}
+	for _, key := range []string{"a\tb", "clé"} {
+		_, err := idempotencyKey(requestWithKey(key))
+		wantStatus(t, err, http.StatusBadRequest)
+	}
+}
+
+func TestRequestHashSeparatesParts(t *testing.T) {
+	hash := requestHash("7", `{"1":"2"}`)
+	if len(hash) != 64 || hash != requestHash("7", `{"1":"2"}`) {
+		t.Errorf("Want the same hex SHA-256 for the same request, got '%s'", hash)
+	}
+	for _, parts := range [][]string{{"7{", `"1":"2"}`}, {`7{"1":"2"}`}, {"8", `{"1":"2"}`}} {
+		if requestHash(parts...) == hash {
+			t.Errorf("Want another hash for %q", parts)
+		}
+	}
+}
+
+func TestIdempotencyRecordReplay(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"message":"result saved."}`)
+	rec := idempotencyRecord{RequestHash: "h", Status: sql.NullInt64{Int64: 201, Valid: true}, Response: body, DateCreated: now.Add(-time.Hour)}
+
+	stored, err := rec.resolve("h", now)
+	if err != nil || stored == nil {
+		t.Fatalf("Want the stored response, got %v", err)
+	}
+	w := httptest.NewRecorder()
+	stored.replay(w)
+	if w.Code != http.StatusCreated || w.Body.String() != string(body) || w.Header().Get(idempotentReplayHeader) != "true" {
+		t.Errorf("Want the response replayed, got %d %s %v", w.Code, w.Body, w.Header())
+	}
+}
+
+func TestIdempotencyRecordOtherRequest(t *testing.T) {
+	now := time.Now()
+	for _, rec := range []idempotencyRecord{
+		{RequestHash: "other", Status: sql.NullInt64{Int64: 201, Valid: true}, DateCreated: now},
+		{RequestHash: "other", DateCreated: now},
+	} {
+		stored, err := rec.resolve("h", now)
+		var httpErr *HTTPError
+		if stored != nil || !errors.As(err, &httpErr) || httpErr.Status != http.StatusUnprocessableEntity || httpErr.Code != CodeIdempotencyKeyReused {
+			t.Errorf("Want the key refused for another request, got %v", err)
+		}
+	}
+}
+
+func TestIdempotencyRecordInProgress(t *testing.T) {
+	now := time.Now()
+	rec := idempotencyRecord{RequestHash: "h", DateCreated: now.Add(-idempotencyInProgressTimeout)}
+	stored, err := rec.resolve("h", now)
+	if stored != nil {
+		t.Error("Want no stored response")
+	}
+	wantStatus(t, err, http.StatusConflict)
+
+	if stored, err := rec.resolve("h", now.Add(time.Second)); stored != nil || err != nil {
+		t.Errorf("Want an abandoned key taken over, got %v", err)
+	}
+}
+
+func TestReserveIdempotencyKeyReplay(t *testing.T) {
+	d, restore := useStubDB(t, map[string][][]driver.Value{
+		"SELECT request_hash": {{"h", int64(201), []byte(`{"score":100}`), time.Now().Add(-time.Hour)}},
+	})
+	defer restore()
+
+	stored, err := reserveIdempotencyKey("alice", "k", "h")
+	if err != nil || stored == nil || stored.status != http.StatusCreated || string(stored.body) != `{"score":100}` {
+		t.Fatalf("Want the response of the first request, got %+v, %v", stored, err)
+	}
+	if len(d.ran("INSERT INTO idempotency_key")) != 1 || len(d.ran("UPDATE idempotency_key")) != 0 {
+		t.Errorf("Want the key looked up after the reservation failed, got %v", d.statements)
+	}
+}
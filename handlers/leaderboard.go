@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"PamQ/sessions"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+func LeaderboardHandler(w http.ResponseWriter, r *http.Request) error {
+	quizID, err := getQuizIdParam(r)
+	if err != nil {
+		return err
+	}
+
+	limit := defaultLeaderboardSize
+	if v := r.URL.Query().Get("limit"); len(v) != 0 {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > maxLeaderboardSize {
+			return NewClientError(err, http.StatusBadRequest, fmt.Sprintf("Invalid query: Please enter a number between 1 and %d for limit.", maxLeaderboardSize))
+		}
+	}
+
+	quiz, err := getQuiz(quizID)
+	if err != nil {
+		return err
+	}
+	if !quiz.Leaderboard {
+		return NewClientError(nil, http.StatusNotFound, "Leaderboard is not enabled for this quiz")
+	}
+
+	username, _ := sessions.GetUsername(r)
+	board, err := getLeaderboard(quiz, username, limit)
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, board)
+}
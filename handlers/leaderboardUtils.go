@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	db "PamQ/database"
+)
+
+const (
+	defaultLeaderboardSize = 10
+	maxLeaderboardSize     = 100
+)
+
+type LeaderboardEntry struct {
+	Rank        int      `json:"rank"`
+	Name        string   `json:"name"`
+	Score       float64  `json:"score"`
+	CompletedAt JSONTime `json:"completed_at"`
+	You         bool     `json:"you,omitempty"`
+}
+
+type Leaderboard struct {
+	QuizID       int                `json:"quiz_id"`
+	Participants int                `json:"participants"`
+	Entries      []LeaderboardEntry `json:"entries"`
+	Me           *LeaderboardEntry  `json:"me,omitempty"`
+}
+
+// leaderboardQuery ranks each participant's best attempt by score, earlier
+// completion breaking ties.
+const leaderboardQuery = `SELECT rank, username, display_name, score, date_created, participants FROM (
+		SELECT attempts.username, COALESCE(u.display_name, attempts.username) AS display_name, score, attempts.date_created,
+			RANK() OVER (ORDER BY score DESC, attempts.date_created ASC) AS rank,
+			COUNT(*) OVER () AS participants
+		FROM (
+			SELECT username, score, date_created,
+				ROW_NUMBER() OVER (PARTITION BY username ORDER BY score DESC, date_created ASC) AS attempt
+			FROM quiz_participation
+			WHERE quiz_id=$1 AND score IS NOT NULL
+		) attempts
+		JOIN userinfo u ON u.username = attempts.username
+		WHERE attempt = 1
+	) ranked`
+
+// leaderboardName returns the name shown to viewer for the participant
+// username. Unless the quiz shows names, participants only see their own.
+func leaderboardName(quiz Quiz, username, displayName, viewer string) string {
+	if quiz.LeaderboardShowNames || (len(viewer) != 0 && username == viewer) {
+		return displayName
+	}
+	return "Anonymous"
+}
+
+// getLeaderboard returns the top limit entries of the quiz leaderboard and
+// the entry of username, if they took the quiz.
+func getLeaderboard(quiz Quiz, username string, limit int) (Leaderboard, error) {
+	board := Leaderboard{QuizID: quiz.Id, Entries: []LeaderboardEntry{}}
+
+	db := db.DB
+	rows, err := db.Query(leaderboardQuery+` WHERE rank <= $2 OR username = $3 ORDER BY rank, username`, quiz.Id, limit, username)
+	if err != nil {
+		return board, NewServerError(err, 500, "Error fetching data from database")
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry LeaderboardEntry
+		var name, displayName string
+		if err := rows.Scan(&entry.Rank, &name, &displayName, &entry.Score, &entry.CompletedAt, &board.Participants); err != nil {
+			return board, NewServerError(err, 500, "Error fetching data from database")
+		}
+		entry.You = len(username) != 0 && name == username
+		entry.Name = leaderboardName(quiz, name, displayName, username)
+
+		if entry.You {
+			me := entry
+			board.Me = &me
+		}
+		if len(board.Entries) < limit {
+			board.Entries = append(board.Entries, entry)
+		}
+	}
+	return board, nil
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import "testing"
+
+func TestLeaderboardNameAnonymizes(t *testing.T) {
+	quiz := Quiz{Leaderboard: true}
+
+	if got := leaderboardName(quiz, "sara", "Sara S.", "ali"); got != "Anonymous" {
+		t.Errorf("Want other participants anonymized, got '%s'", got)
+	}
+	if got := leaderboardName(quiz, "sara", "Sara S.", ""); got != "Anonymous" {
+		t.Errorf("Want participants anonymized for visitors, got '%s'", got)
+	}
+	if got := leaderboardName(quiz, "sara", "Sara S.", "sara"); got != "Sara S." {
+		t.Errorf("Want participants to see their own name, got '%s'", got)
+	}
+	// A visitor isn't anyone's own entry.
+	if got := leaderboardName(quiz, "", "", ""); got != "Anonymous" {
+		t.Errorf("Want a visitor to see no name, got '%s'", got)
+	}
+}
+
+func TestLeaderboardNameShown(t *testing.T) {
+	quiz := Quiz{Leaderboard: true, LeaderboardShowNames: true}
+
+	for _, viewer := range []string{"ali", "sara", ""} {
+		if got := leaderboardName(quiz, "sara", "Sara S.", viewer); got != "Sara S." {
+			t.Errorf("Viewer '%s': want the display name, got '%s'", viewer, got)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"PamQ/logging"
+	"PamQ/sessions"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+type requestInfoKey struct{}
+
+// requestInfo collects what handlers learn about a request for its access
+// log entry.
+type requestInfo struct {
+	user string
+}
+
+// statusRecorder remembers the status and size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += n
+	return n, err
+}
+
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// validRequestID accepts IDs set by a proxy in front of the server, as long
+// as they can't garble the logs.
+func validRequestID(id string) bool {
+	if len(id) == 0 || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// requestLogger returns the logger of r, which includes its request ID.
+func requestLogger(r *http.Request) *logging.Logger {
+	return logging.FromContext(r.Context())
+}
+
+// setRequestUser names the user of r in its access log entry.
+func setRequestUser(r *http.Request, username string) {
+	if info, ok := r.Context().Value(requestInfoKey{}).(*requestInfo); ok {
+		info.user = username
+	}
+}
+
+// RequestLogger gives every request an ID, echoed in the X-Request-ID
+// response header, and a logger carrying it. Once the request is served it
+// writes an access log entry, naming the user if the handler looked up a
+// session or token.
+func RequestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		info := &requestInfo{}
+		logger := logging.Default.With("request_id", id)
+		ctx := logging.NewContext(r.Context(), logger)
+		ctx = context.WithValue(ctx, requestInfoKey{}, info)
+		// The session cache is installed here so that the session looked up
+		// by the handler, if any, can name the user without a second lookup.
+		r = sessions.WithCache(r.WithContext(ctx))
+
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		if s, ok := sessions.Cached(r); ok && len(info.user) == 0 {
+			setRequestUser(r, s.Username)
+		}
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		logger.Info("request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+			"user", info.user,
+			"ip", clientIP(r))
+	})
+}
+
+// causes lists the messages of the errors wrapped by err, outermost first.
+func causes(err error) []string {
+	var list []string
+	for ; err != nil; err = errors.Unwrap(err) {
+		list = append(list, err.Error())
+	}
+	return list
+}
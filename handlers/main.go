@@ -4,8 +4,6 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
-
-	"github.com/lib/pq"
 )
 
 type RootHandler func(http.ResponseWriter, *http.Request) error
@@ -15,43 +13,34 @@ func (fn RootHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err == nil {
 		return
 	}
-	log.Printf("An error accured: %v %T", err, err)
+	writeError(w, r, err)
+}
 
-	httpError, ok := err.(*HTTPError)
-	if !ok {
-		log.Println("Not http error")
-		w.WriteHeader(500)
-		return
+// writeError responds with err as a problem+json document. Server errors are
+// logged with their cause, which is never sent to the client.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	httpError := toHTTPError(err)
+	logger := requestLogger(r).With("status", httpError.Status, "error", httpError.Detail, "cause", causes(httpError.Cause))
+	if httpError.Type == ServerError {
+		logger.Error("server error")
+	} else {
+		logger.Info("client error")
 	}
 
-	switch httpError.Type {
-	case ClientError:
-		pqErr, ok := httpError.Cause.(*pq.Error)
-		if ok {
-			httpError.Detail = pqErr.Detail
-		}
-		log.Println("client error")
-		body, err := httpError.ResponseBody()
-		if err != nil {
-			log.Printf("An error accured: %v", err)
-			w.WriteHeader(500)
-		}
-		status, headers := httpError.ResponseHeaders()
-		for k, v := range headers {
-			w.Header().Set(k, v)
-		}
-		w.WriteHeader(status)
-		w.Write(body)
-		return
-	case ServerError:
-		log.Println("server error")
-		pqErr := err.(*pq.Error)
-		log.Println(pqErr.Code)
-		w.WriteHeader(500)
+	problem := httpError.Problem()
+	problem.Instance = r.URL.Path
+	body, err := json.Marshal(problem)
+	if err != nil {
+		logger.Error("error encoding response body", "cause", causes(err))
+		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
-
-	log.Println(err)
+	status, headers := httpError.ResponseHeaders()
+	for k, v := range headers {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(status)
+	w.Write(body)
 }
 
 func EmptyHandler(w http.ResponseWriter, r *http.Request) {
@@ -66,3 +55,14 @@ func EmptyHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(js)
 
 }
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) error {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return NewServerError(err, 500, "Error while parsing response body")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+	return nil
+}
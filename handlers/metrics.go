@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"PamQ/config"
+	db "PamQ/database"
+	"PamQ/metrics"
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	httpRequests = metrics.NewCounter("pamq_http_requests_total",
+		"HTTP requests by method, route template and status.", "method", "route", "status")
+	httpDuration = metrics.NewHistogram("pamq_http_request_duration_seconds",
+		"HTTP request latency by method and route template.", metrics.DefaultBuckets, "method", "route")
+
+	quizzesCreated = metrics.NewCounter("pamq_quizzes_created_total",
+		"Quizzes created.")
+	quizViews = metrics.NewCounter("pamq_quiz_views_total",
+		"Quiz pages viewed by logged in users having participations left.")
+	attemptsSubmitted = metrics.NewCounter("pamq_quiz_attempts_submitted_total",
+		"Quiz answers submitted, by result.", "result")
+	submissionsReplayed = metrics.NewCounter("pamq_quiz_submissions_replayed_total",
+		"Retried quiz submissions answered with the stored result.")
+	loginFailures = metrics.NewCounter("pamq_login_failures_total",
+		"Failed logins by reason.", "reason")
+	deprecatedRequests = metrics.NewCounter("pamq_deprecated_requests_total",
+		"Requests to deprecated routes, by route template.", "route")
+)
+
+func init() {
+	stats := func(fn func(s sql.DBStats) float64) func() float64 {
+		return func() float64 { return fn(db.DB.Stats()) }
+	}
+	metrics.NewGaugeFunc("pamq_db_max_open_connections", "Maximum number of open database connections.",
+		stats(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	metrics.NewGaugeFunc("pamq_db_open_connections", "Open database connections.",
+		stats(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	metrics.NewGaugeFunc("pamq_db_in_use_connections", "Database connections in use.",
+		stats(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	metrics.NewGaugeFunc("pamq_db_idle_connections", "Idle database connections.",
+		stats(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	metrics.NewCounterFunc("pamq_db_wait_count_total", "Times a database connection was waited for.",
+		stats(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	metrics.NewCounterFunc("pamq_db_wait_duration_seconds_total", "Time spent waiting for database connections.",
+		stats(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	metrics.NewCounterFunc("pamq_db_max_idle_closed_total", "Connections closed because of the idle connection limit.",
+		stats(func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }))
+	metrics.NewCounterFunc("pamq_db_max_lifetime_closed_total", "Connections closed because of their maximum lifetime.",
+		stats(func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }))
+}
+
+// resultLabel is the result of a participation in the metrics.
+func resultLabel(pass bool) string {
+	if pass {
+		return "pass"
+	}
+	return "fail"
+}
+
+// routeName returns the path template of the matched route, which keeps the
+// number of series small: "/api/v1/quiz/{quizID}" rather than every quiz ID.
+func routeName(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "unmatched"
+	}
+	if tpl, err := route.GetPathTemplate(); err == nil {
+		return tpl
+	}
+	return "unknown"
+}
+
+type routeKey struct{}
+
+// Metrics records the count and latency of requests. It wraps the whole
+// router so that requests matching no route, answered with 404 or 405, are
+// counted too; RecordRoute names the route of the others.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := "unmatched"
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), routeKey{}, &route)))
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		httpRequests.Inc(r.Method, route, strconv.Itoa(rec.status))
+		httpDuration.Observe(time.Since(start).Seconds(), r.Method, route)
+	})
+}
+
+// RecordRoute is a router middleware telling Metrics the matched route.
+func RecordRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route, ok := r.Context().Value(routeKey{}).(*string); ok {
+			*route = routeName(r)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// metricsToken, when set, must be sent as a bearer token to read the metrics.
+// Without it the metrics are public, so a deployment that doesn't set it must
+// keep /metrics off the public network.
+var metricsToken = config.String("METRICS_TOKEN", "")
+
+// MetricsHandler serves the metrics in the Prometheus text format.
+func MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	if len(metricsToken) != 0 {
+		auth := r.Header.Get("Authorization")
+		if subtle.ConstantTimeCompare([]byte(auth), []byte("Bearer "+metricsToken)) != 1 {
+			reject(w, r, NewClientError(nil, http.StatusUnauthorized, "Invalid metrics token"))
+			return
+		}
+	}
+	metrics.Default.Handler().ServeHTTP(w, r)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"PamQ/apitokens"
+	"PamQ/sessions"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BearerAuth authenticates requests carrying an "Authorization: Bearer"
+// API token. Other requests are passed through unchanged.
+func BearerAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if len(auth) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		const prefix = "Bearer "
+		if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+			reject(w, r, NewClientError(nil, http.StatusUnauthorized, "Unsupported authorization scheme"))
+			return
+		}
+		token, err := apitokens.Resolve(strings.TrimSpace(auth[len(prefix):]))
+		if err == apitokens.ErrNotFound {
+			reject(w, r, NewClientError(err, http.StatusUnauthorized, "Invalid or expired token"))
+			return
+		} else if err != nil {
+			reject(w, r, NewServerError(err, 500, "Error fetching data from database"))
+			return
+		}
+
+		setRequestUser(r, token.Username)
+		next.ServeHTTP(w, sessions.WithToken(r, token.Username, token.Scopes))
+	})
+}
+
+// reject writes err the same way a RootHandler would.
+func reject(w http.ResponseWriter, r *http.Request, err error) {
+	writeError(w, r, err)
+}
+
+// WithScope lets requests made with an API token reach fn when the token has
+// scope. To handlers without a scope, token requests are not logged in.
+func WithScope(scope string, fn RootHandler) RootHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		r, ok := sessions.Grant(r, scope)
+		if !ok {
+			return NewClientError(nil, http.StatusForbidden, fmt.Sprintf("Token doesn't have the %s scope", scope))
+		}
+		return fn(w, r)
+	}
+}
+
+// WithPublicScope is WithScope for routes anyone may use. Token requests
+// lacking scope reach fn as anonymous requests instead of being refused.
+func WithPublicScope(scope string, fn RootHandler) RootHandler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		r, _ = sessions.Grant(r, scope)
+		return fn(w, r)
+	}
+}
+
+// Deprecated announces in the Deprecation (RFC 9745) and Sunset (RFC 8594)
+// headers that the routes of next are deprecated since date and, when sunset
+// isn't zero, removed at sunset. successor maps the request path to the path
+// replacing it, linked as the successor version.
+func Deprecated(date, sunset time.Time, successor func(path string) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(date.Unix(), 10))
+			if !sunset.IsZero() {
+				w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			if successor != nil {
+				w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor(r.URL.Path)))
+			}
+			deprecatedRequests.Inc(routeName(r))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
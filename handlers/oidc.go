@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"PamQ/oidc"
+	"PamQ/sessions"
+	"PamQ/tokens"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	oidcCookieName = "oidc_flow"
+	oidcFlowTTL    = 10 * time.Minute
+)
+
+func setOIDCCookie(w http.ResponseWriter, value string) {
+	cookie := &http.Cookie{
+		Name:  oidcCookieName,
+		Value: value,
+		// The login and callback paths may be of different API versions.
+		Path:     "/api/",
+		HttpOnly: true,
+		Secure:   sessions.SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if len(value) == 0 {
+		cookie.MaxAge = -1
+	} else {
+		cookie.MaxAge = int(oidcFlowTTL.Seconds())
+	}
+	http.SetCookie(w, cookie)
+}
+
+// OIDCLoginHandler redirects to the provider. The state, nonce and PKCE
+// verifier of the flow are kept in a signed cookie until the callback.
+func OIDCLoginHandler(w http.ResponseWriter, r *http.Request) error {
+	provider, err := getOIDCProvider(r)
+	if err != nil {
+		return err
+	}
+
+	var flow [3]string
+	for i := range flow {
+		if flow[i], err = oidc.RandomString(); err != nil {
+			return NewServerError(err, 500, "Error starting sign in")
+		}
+	}
+	state, nonce, verifier := flow[0], flow[1], flow[2]
+
+	authURL, err := provider.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		return NewServerError(err, http.StatusBadGateway, "Sign in provider unavailable")
+	}
+
+	setOIDCCookie(w, tokens.Default.Sign(oidcFlowPurpose, strings.Join(flow[:], " "), provider.Name, oidcFlowTTL))
+	http.Redirect(w, r, authURL, http.StatusFound)
+	return nil
+}
+
+func OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) error {
+	provider, err := getOIDCProvider(r)
+	if err != nil {
+		return err
+	}
+
+	query := r.URL.Query()
+	if len(query.Get("error")) != 0 {
+		return NewClientError(nil, http.StatusUnauthorized, "Sign in was cancelled or failed: "+query.Get("error"))
+	}
+
+	cookie, err := r.Cookie(oidcCookieName)
+	if err != nil {
+		return NewClientError(err, http.StatusBadRequest, "Sign in session not found, please try again.")
+	}
+	setOIDCCookie(w, "")
+	subject, err := tokens.Default.Verify(cookie.Value, oidcFlowPurpose, provider.Name)
+	if err != nil {
+		return NewClientError(err, http.StatusBadRequest, "Sign in session expired, please try again.")
+	}
+	flow := strings.Split(subject, " ")
+	if len(flow) != 3 || subtle.ConstantTimeCompare([]byte(flow[0]), []byte(query.Get("state"))) != 1 {
+		return NewClientError(nil, http.StatusBadRequest, "Invalid sign in state, please try again.")
+	}
+	nonce, verifier := flow[1], flow[2]
+
+	claims, err := provider.Exchange(r.Context(), query.Get("code"), verifier, nonce)
+	if err != nil {
+		return NewClientError(err, http.StatusUnauthorized, "Sign in failed.")
+	}
+
+	current, _ := sessions.GetUsername(r)
+	username, err := oidcUser(provider, claims, current)
+	if err != nil {
+		return err
+	}
+	// Signing in with a provider replaces the password, not the second factor.
+	if username != current {
+		user, err := getUserByUsername(username)
+		if err != nil {
+			return err
+		}
+		if user.TOTPEnabled {
+			startTwoFactor(w, user)
+			http.Redirect(w, r, appURL+"/login/2fa", http.StatusFound)
+			return nil
+		}
+	}
+	if err := sessions.Login(w, r, username); err != nil {
+		return NewServerError(err, 500, "Sessions login error")
+	}
+	http.Redirect(w, r, appURL+"/", http.StatusFound)
+	return nil
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	db "PamQ/database"
+	"PamQ/oidc"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/gorilla/mux"
+)
+
+const oidcFlowPurpose = "oidc-flow"
+
+var oidcProviders = oidc.ProvidersFromConfig()
+
+func getOIDCProvider(r *http.Request) (*oidc.Provider, error) {
+	provider, ok := oidcProviders[mux.Vars(r)["provider"]]
+	if !ok {
+		return nil, NewClientError(nil, http.StatusNotFound, "Sign in provider not found")
+	}
+	return provider, nil
+}
+
+// usernameFromClaims turns the preferred username or email of an identity
+// into a username accepted by NewUser.validate.
+func usernameFromClaims(claims *oidc.Claims) string {
+	candidate := claims.PreferredUsername
+	if len(candidate) == 0 {
+		candidate = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+
+	parts := strings.FieldsFunc(candidate, func(c rune) bool {
+		return c > unicode.MaxASCII || !(unicode.IsLetter(c) || unicode.IsDigit(c))
+	})
+	name := strings.Join(parts, "_")
+	if len(name) > 0 && !unicode.IsLetter(rune(name[0])) {
+		name = "u" + name
+	}
+	if len(name) > 26 {
+		name = strings.TrimRight(name[:26], "_")
+	}
+	if len(name) < 3 {
+		name = "user"
+	}
+	return name
+}
+
+func getLinkedUser(provider, subject string) (string, error) {
+	var username string
+	db := db.DB
+	err := db.QueryRow(`SELECT username FROM user_identity WHERE provider=$1 AND subject=$2`, provider, subject).Scan(&username)
+	if err == sql.ErrNoRows {
+		return "", nil
+	} else if err != nil {
+		return "", NewServerError(err, 500, "Error fetching data from database")
+	}
+	return username, nil
+}
+
+func linkIdentity(db execer, provider string, claims *oidc.Claims, username string) error {
+	if _, err := db.Exec(`INSERT INTO user_identity (provider, subject, username, email) VALUES ($1, $2, $3, $4)`,
+		provider, claims.Subject, username, claims.Email); err != nil {
+		return NewServerError(err, 500, "Identity not saved in database")
+	}
+	return nil
+}
+
+// createOIDCUser creates a user without a password for a new identity. The
+// email is only kept when the provider verified it and no other account uses
+// it.
+func createOIDCUser(provider string, claims *oidc.Claims) (string, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return "", NewServerError(err, 500, "Error starting database transaction")
+	}
+	defer tx.Rollback()
+
+	var email interface{}
+	if claims.EmailVerified && validateEmail(claims.Email) {
+		var taken bool
+		if err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM userinfo WHERE email=LOWER($1))`, claims.Email).Scan(&taken); err != nil {
+			return "", NewServerError(err, 500, "Error fetching data from database")
+		}
+		if !taken {
+			email = claims.Email
+		}
+	}
+
+	base := usernameFromClaims(claims)
+	username := ""
+	for i := 0; i < 100 && len(username) == 0; i++ {
+		candidate := base
+		if i > 0 {
+			candidate = fmt.Sprintf("%s%d", base, i)
+		}
+		res, err := tx.Exec(`INSERT INTO userinfo (username, email, email_verified, display_name) VALUES ($1, LOWER($2), $3, NULLIF($4, ''))
+			ON CONFLICT (username) DO NOTHING`, candidate, email, email != nil, claims.Name)
+		if err != nil {
+			return "", NewServerError(err, 500, "Create user error")
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 1 {
+			username = candidate
+		}
+	}
+	if len(username) == 0 {
+		return "", NewServerError(nil, 500, "Could not find a free username")
+	}
+
+	if err := linkIdentity(tx, provider, claims, username); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", NewServerError(err, 500, "Create user error")
+	}
+	return username, nil
+}
+
+// linkByEmail returns the user an identity with claims may be linked to as
+// the owner of the same email. Both sides must have verified it, otherwise
+// whoever registered the address on one of them could take over the other.
+func linkByEmail(claims *oidc.Claims, user *User) (string, error) {
+	if !claims.EmailVerified || !user.EmailVerified {
+		return "", NewCodedError(nil, http.StatusConflict, CodeConflict,
+			"An account already uses this email. Please log in to it and link this sign in provider from there.")
+	}
+	return user.Username, nil
+}
+
+// oidcUser returns the user to log in for an identity, linking it on first
+// login to the logged in user, to the user with the same verified email when
+// the provider allows it, or else to a new user.
+func oidcUser(provider *oidc.Provider, claims *oidc.Claims, current string) (string, error) {
+	username, err := getLinkedUser(provider.Name, claims.Subject)
+	if err != nil || len(username) != 0 {
+		return username, err
+	}
+
+	if len(current) == 0 && provider.LinkByEmail && len(claims.Email) != 0 {
+		if user, err := getUserByEmail(claims.Email); err == nil {
+			if current, err = linkByEmail(claims, user); err != nil {
+				return "", err
+			}
+		}
+	}
+	if len(current) != 0 {
+		return current, linkIdentity(db.DB, provider.Name, claims, current)
+	}
+	return createOIDCUser(provider.Name, claims)
+}
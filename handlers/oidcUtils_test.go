@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"PamQ/oidc"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestLinkByEmail(t *testing.T) {
+	tt := []struct {
+		name                           string
+		providerVerified, userVerified bool
+		link                           bool
+	}{
+		{name: "Both verified", providerVerified: true, userVerified: true, link: true},
+		{name: "Unverified account", providerVerified: true, userVerified: false},
+		{name: "Unverified identity", providerVerified: false, userVerified: true},
+		{name: "Neither verified"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			claims := &oidc.Claims{Subject: "42", Email: "alice@example.com", EmailVerified: tc.providerVerified}
+			user := &User{Username: "alice", Email: "alice@example.com", EmailVerified: tc.userVerified}
+
+			username, err := linkByEmail(claims, user)
+			if tc.link {
+				if err != nil || username != "alice" {
+					t.Errorf("Want the identity linked to alice, got '%s', %v", username, err)
+				}
+				return
+			}
+			var herr *HTTPError
+			if !errors.As(err, &herr) || herr.Status != http.StatusConflict || len(username) != 0 {
+				t.Errorf("Want a conflict asking to link from the account, got '%s', %v", username, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxQuestionTextLength is the size of the text columns of question.
+const maxQuestionTextLength = 500
+
+// checkQuestionText records an error on field in errs when value doesn't fit
+// its column.
+func checkQuestionText(errs *FieldErrors, field, value string) {
+	if utf8.RuneCountInString(value) > maxQuestionTextLength {
+		errs.add(invalidField(field, fmt.Sprintf("Please enter at most %d characters.", maxQuestionTextLength)))
+	}
+}
+
+// questionPayload is the body of a new question of one type.
+type questionPayload interface {
+	validate() error
+	question() Question
+}
+
+// questionPayloads maps each question type to the payload it's decoded into.
+var questionPayloads = map[QuestionType]func() questionPayload{
+	MultiChoice: func() questionPayload { return &MultiChoiceQuestion{} },
+	ShortAnswer: func() questionPayload { return &ShortAnswerQuestion{} },
+}
+
+// UnmarshalJSON accepts the type as a number or, as older clients send it, a
+// numeric string.
+func (t *QuestionType) UnmarshalJSON(b []byte) error {
+	n, err := strconv.Atoi(strings.Trim(string(b), `"`))
+	if _, ok := questionPayloads[QuestionType(n)]; err != nil || !ok {
+		return invalidField("type", "Please enter a valid type for question. (1 for multichoice question or 2 for short answer)")
+	}
+	*t = QuestionType(n)
+	return nil
+}
+
+// NewQuestion is a question of a quiz being created or edited. Its type
+// decides the payload the rest of it is decoded into, so fields that don't
+// belong to the type are rejected.
+type NewQuestion struct {
+	Type    QuestionType
+	Payload questionPayload
+}
+
+func (q *NewQuestion) UnmarshalJSON(b []byte) error {
+	var head struct {
+		Type *QuestionType `json:"type"`
+	}
+	if err := json.Unmarshal(b, &head); err != nil {
+		return err
+	}
+	if head.Type == nil {
+		return ErrorMissingField("type")
+	}
+
+	payload := questionPayloads[*head.Type]()
+	if err := json.Unmarshal(b, payload); err != nil {
+		return err
+	}
+	q.Type, q.Payload = *head.Type, payload
+	return nil
+}
+
+func (q NewQuestion) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.Payload)
+}
+
+// MultiChoiceQuestion has two to four options. Its answer, if given, is the
+// number of the right option.
+type MultiChoiceQuestion struct {
+	ID        int          `json:"id"`
+	Type      QuestionType `json:"type"`
+	Statement string       `json:"statement"`
+	Option1   string       `json:"option1"`
+	Option2   string       `json:"option2"`
+	Option3   string       `json:"option3"`
+	Option4   string       `json:"option4"`
+	Answer    string       `json:"answer"`
+}
+
+func (q *MultiChoiceQuestion) UnmarshalJSON(b []byte) error {
+	type plain MultiChoiceQuestion
+	return decodeStrict(b, (*plain)(q))
+}
+
+func (q *MultiChoiceQuestion) validate() error {
+	var errs FieldErrors
+	if len(q.Statement) == 0 {
+		errs.add(ErrorMissingField("statement"))
+	}
+	if len(q.Option1) == 0 {
+		errs.add(ErrorMissingField("option1"))
+	}
+	if len(q.Option2) == 0 {
+		errs.add(ErrorMissingField("option2"))
+	}
+	checkQuestionText(&errs, "statement", q.Statement)
+	checkQuestionText(&errs, "option1", q.Option1)
+	checkQuestionText(&errs, "option2", q.Option2)
+	checkQuestionText(&errs, "option3", q.Option3)
+	checkQuestionText(&errs, "option4", q.Option4)
+	if len(q.Answer) != 0 {
+		answer, err := strconv.Atoi(q.Answer)
+		if err != nil || answer < 1 || answer > 4 {
+			errs.add(invalidField("answer", "Please enter a valid number as answer for question."))
+		}
+	}
+	return errs.err()
+}
+
+func (q *MultiChoiceQuestion) question() Question {
+	return Question{
+		Id:        q.ID,
+		QType:     MultiChoice,
+		Statement: q.Statement,
+		Option1:   q.Option1,
+		Option2:   q.Option2,
+		Option3:   q.Option3,
+		Option4:   q.Option4,
+		Answer:    q.Answer,
+	}
+}
+
+// ShortAnswerQuestion is answered with text, compared without regard to case.
+type ShortAnswerQuestion struct {
+	ID        int          `json:"id"`
+	Type      QuestionType `json:"type"`
+	Statement string       `json:"statement"`
+	Answer    string       `json:"answer"`
+}
+
+func (q *ShortAnswerQuestion) UnmarshalJSON(b []byte) error {
+	type plain ShortAnswerQuestion
+	return decodeStrict(b, (*plain)(q))
+}
+
+func (q *ShortAnswerQuestion) validate() error {
+	var errs FieldErrors
+	if len(q.Statement) == 0 {
+		errs.add(ErrorMissingField("statement"))
+	}
+	checkQuestionText(&errs, "statement", q.Statement)
+	checkQuestionText(&errs, "answer", q.Answer)
+	return errs.err()
+}
+
+func (q *ShortAnswerQuestion) question() Question {
+	return Question{Id: q.ID, QType: ShortAnswer, Statement: q.Statement, Answer: q.Answer}
+}
+
+// UnmarshalJSON decodes the questions one by one so that their errors can
+// name the question at fault.
+func (q *NewQuiz) UnmarshalJSON(b []byte) error {
+	type plain NewQuiz
+	var raw struct {
+		*plain
+		Questions []json.RawMessage `json:"questions"`
+	}
+	raw.plain = (*plain)(q)
+	if err := decodeStrict(b, &raw); err != nil {
+		return err
+	}
+
+	q.Questions = make([]NewQuestion, len(raw.Questions))
+	for i, js := range raw.Questions {
+		if err := json.Unmarshal(js, &q.Questions[i]); err != nil {
+			return prefixField("questions["+strconv.Itoa(i)+"]", err)
+		}
+	}
+	return nil
+}
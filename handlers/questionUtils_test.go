@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestQuestionTextLength(t *testing.T) {
+	fits := strings.Repeat("é", maxQuestionTextLength)
+	long := strings.Repeat("a", maxQuestionTextLength+1)
+	body := fmt.Sprintf(`{"name": "Lengths", "grading_type": 1, "allowed_participation": 1, "questions": [
+		{"type": 1, "statement": %[1]q, "option1": %[1]q, "option2": "b", "option3": %[2]q},
+		{"type": 2, "statement": %[2]q, "answer": %[2]q},
+		{"type": 1, "statement": %[2]q, "option1": "a", "option2": %[2]q, "option4": %[2]q}
+	]}`, fits, long)
+
+	var newQuiz NewQuiz
+	if err := json.Unmarshal([]byte(body), &newQuiz); err != nil {
+		t.Fatal(err)
+	}
+	_, err := newQuiz.validate()
+	var errs FieldErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("Want field errors, got %v", err)
+	}
+
+	got := map[string]bool{}
+	for _, fieldErr := range errs {
+		got[fieldErr.Field] = true
+	}
+	want := []string{"questions[0].option3", "questions[1].statement", "questions[1].answer",
+		"questions[2].statement", "questions[2].option2", "questions[2].option4"}
+	for _, field := range want {
+		if !got[field] {
+			t.Errorf("Want an error on %s, got %v", field, errs)
+		}
+	}
+	if len(errs) != len(want) {
+		t.Errorf("Want %d errors, got %v", len(want), errs)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 func CreateQuizHandler(w http.ResponseWriter, r *http.Request) error {
@@ -16,15 +18,17 @@ func CreateQuizHandler(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	var newQuiz NewQuiz
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&newQuiz); err != nil {
-		return NewClientError(err, 400, "Bad request : invalid JSON.")
+	if err := decodeJSON(w, r, &newQuiz); err != nil {
+		return err
 	}
 
 	quiz, err := newQuiz.validate()
 	if err != nil {
 		return NewClientError(err, http.StatusBadRequest, fmt.Sprintf("Invalid form data: %s", err.Error()))
 	}
+	if err := checkCategory("category_id", quiz.CategoryID); err != nil {
+		return err
+	}
 
 	var ok bool
 	quiz.Creator, ok = sessions.GetUsername(r)
@@ -32,10 +36,25 @@ func CreateQuizHandler(w http.ResponseWriter, r *http.Request) error {
 		return NewServerError(nil, 500, "Error getting username from session")
 	}
 
-	quizID, err := quiz.addToDB()
+	// The quiz and its audit entry are saved together, so that a quiz is
+	// never created without one.
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return NewServerError(err, 500, "Error starting database transaction")
+	}
+	defer tx.Rollback()
+
+	quizID, err := quiz.addToDB(tx)
 	if err != nil {
 		return err
 	}
+	if err := addAuditEntry(tx, quizID, quiz.Creator, "quiz.create", quiz.Name); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return NewServerError(err, 500, "Quiz not saved in database")
+	}
+	quizzesCreated.Inc()
 
 	mp := map[string]interface{}{"message": "Quiz created.", "id": quizID}
 	js, err := json.Marshal(mp)
@@ -53,51 +72,31 @@ func QuizHandler(w http.ResponseWriter, r *http.Request) error {
 	if err != nil {
 		return err
 	}
-	var quiz Quiz
-
-	db := db.DB
-	err = db.QueryRow(`SELECT * FROM quiz WHERE id=$1`, quizID).Scan(&quiz.Id, &quiz.Creator, &quiz.Name, &quiz.GradingType, &quiz.PassFail, &quiz.PassingScore, &quiz.NotFailText, &quiz.FailText, &quiz.AllowedParticipations, &quiz.DateCreated)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return NewClientError(err, http.StatusNotFound, "Quiz not found")
-		}
-		return NewServerError(err, 500, "Error fetching data from database")
-	}
-
-	rows, err := db.Query(`SELECT * FROM question WHERE quiz_id=$1`, quizID)
+	quiz, err := getQuiz(quizID)
 	if err != nil {
-		return NewServerError(err, 500, "Error fetching data from database")
+		return err
 	}
 
-	for rows.Next() {
-		var q Question
-		err = rows.Scan(&q.Id, &q.QuizID, &q.QType, &q.Statement, &q.Option1, &q.Option2, &q.Option3, &q.Option4, &q.Answer)
-		if err != nil {
-			return NewServerError(err, 500, "Error fetching data from database")
-		}
-		quiz.Questions = append(quiz.Questions, q)
-	}
+	db := db.DB
 	loggedIn := sessions.IsLoggedIn(r)
-	availableParticipation := quiz.AllowedParticipations
-	if loggedIn {
-		// quiz.AllowedParticipations
-		username, ok := sessions.GetUsername(r)
-		if !ok {
-			return NewServerError(nil, 500, "Error getting username from session")
-		}
-		rows, err := db.Query(`SELECT * FROM quiz_participation WHERE quiz_id=$1 AND username=$2`, quizID, username)
-		if err != nil {
-			return NewServerError(err, 500, "Error fetching data from database")
-		}
-		count := 0
-		for rows.Next() {
-			count++
-		}
-		availableParticipation -= count
-	}
 
 	if r.Method == http.MethodGet {
+		availableParticipation := quiz.AllowedParticipations
+		if loggedIn {
+			username, ok := sessions.GetUsername(r)
+			if !ok {
+				return NewServerError(nil, 500, "Error getting username from session")
+			}
+			taken, err := countParticipations(db, quizID, username)
+			if err != nil {
+				return err
+			}
+			availableParticipation -= taken
+		}
+
+		if loggedIn && availableParticipation > 0 {
+			quizViews.Inc()
+		}
 		for i := range quiz.Questions {
 			quiz.Questions[i].Answer = ""
 		}
@@ -105,6 +104,21 @@ func QuizHandler(w http.ResponseWriter, r *http.Request) error {
 		quiz.FailText = ""
 		quiz.NotFailText = ""
 
+		// Anonymous visitors all get the same view. Logged in users also see
+		// their remaining participations, which only their client may keep.
+		w.Header().Set("Vary", "Cookie, Authorization")
+		if loggedIn {
+			w.Header().Set("Cache-Control", privateCacheControl)
+			if notModified(w, r, quizETag(quiz.Version, &availableParticipation), time.Time{}) {
+				return nil
+			}
+		} else {
+			w.Header().Set("Cache-Control", publicCacheControl)
+			if notModified(w, r, quizETag(quiz.Version, nil), time.Time(quiz.UpdatedAt)) {
+				return nil
+			}
+		}
+
 		comb := struct {
 			Quiz
 			AvailableParicipation int `json:"available_participation"`
@@ -126,17 +140,52 @@ func QuizHandler(w http.ResponseWriter, r *http.Request) error {
 		}
 
 		var userAnswers map[string]string
-		decoder := json.NewDecoder(r.Body)
-		if err := decoder.Decode(&userAnswers); err != nil {
-			return NewClientError(err, 400, "Bad request : invalid JSON.")
+		if err := decodeJSON(w, r, &userAnswers); err != nil {
+			return err
 		}
 
-		if availableParticipation <= 0 {
-			return NewClientError(nil, http.StatusBadRequest, "Your participation limit for this quiz has been reached")
+		username, ok := sessions.GetUsername(r)
+		if !ok {
+			return NewServerError(nil, 500, "Error getting username from session")
+		}
+
+		// A retried submission gets the result of the first one instead of
+		// being scored again.
+		key, err := idempotencyKey(r)
+		if err != nil {
+			return err
 		}
+		recorded := false
+		if len(key) != 0 {
+			answersJSON, err := json.Marshal(userAnswers)
+			if err != nil {
+				return NewServerError(err, 500, "Error while parsing request body")
+			}
+			stored, err := reserveIdempotencyKey(username, key, requestHash(strconv.Itoa(quizID), string(answersJSON)))
+			if err != nil {
+				return err
+			}
+			if stored != nil {
+				submissionsReplayed.Inc()
+				stored.replay(w)
+				return nil
+			}
+			// Until the participation is recorded, a failed submission may
+			// be retried with the same key.
+			defer func() {
+				if recorded {
+					return
+				}
+				if err := releaseIdempotencyKey(username, key); err != nil {
+					requestLogger(r).Error("error releasing idempotency key", "user", username, "cause", causes(err))
+				}
+			}()
+		}
+
 		mark := 0.0
 		totalScore := 0.0
 		stats := [4]int{0, 0, 0, 0}
+		var answers []ParticipationAnswer
 		for _, question := range quiz.Questions {
 			userAnswer := userAnswers[strconv.Itoa(question.Id)]
 			res := question.check(userAnswer)
@@ -145,29 +194,33 @@ func QuizHandler(w http.ResponseWriter, r *http.Request) error {
 			if res != QuestionAnswerNotProvided {
 				totalScore += 1
 			}
-		}
-
-		username, ok := sessions.GetUsername(r)
-		if !ok {
-			return NewServerError(nil, 500, "Error getting username from session")
+			answers = append(answers, ParticipationAnswer{
+				QuestionID: question.Id,
+				Answer:     strings.TrimSpace(userAnswer),
+				Result:     res,
+				Mark:       res.Mark(quiz.GradingType)})
 		}
 
 		participation := QuizParticipation{
 			QuizID:   quizID,
 			Username: username,
-			Score:    mark / totalScore * 100}
-
-		if quiz.PassFail && participation.Score < quiz.PassingScore {
-			participation.PassFail = false
-			participation.Result = quiz.FailText
-		} else {
-			participation.PassFail = true
-			participation.Result = quiz.NotFailText
-		}
+			Score:    mark / totalScore * 100,
+			Answers:  answers}
 
-		err = participation.addToDB()
+		quiz.applyResult(&participation)
 
+		// The stored response of a submission sent with a key is saved with
+		// the participation, so that a retry never scores it twice.
+		tx, err := db.Begin()
 		if err != nil {
+			return NewServerError(err, 500, "Error starting database transaction")
+		}
+		defer tx.Rollback()
+
+		if err := checkNewParticipation(tx, quizID, username, time.Now()); err != nil {
+			return err
+		}
+		if err := participation.addToDB(tx); err != nil {
 			return err
 		}
 
@@ -180,6 +233,17 @@ func QuizHandler(w http.ResponseWriter, r *http.Request) error {
 		if err != nil {
 			return NewServerError(err, 500, "Error while parsing response body")
 		}
+		if len(key) != 0 {
+			if err := saveIdempotentResponse(tx, username, key, http.StatusCreated, js); err != nil {
+				return err
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return NewServerError(err, 500, "Quiz participation not saved in database")
+		}
+		recorded = true
+		attemptsSubmitted.Inc(resultLabel(participation.PassFail))
+
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusCreated)
 		w.Write(js)
@@ -188,44 +252,77 @@ func QuizHandler(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
-func ListOfQuizesHandler(w http.ResponseWriter, r *http.Request) error {
+func EditQuizHandler(w http.ResponseWriter, r *http.Request) error {
+	quizID, err := getQuizIdParam(r)
+	if err != nil {
+		return err
+	}
+	username, err := requireQuizPermission(r, quizID, EditQuiz)
+	if err != nil {
+		return err
+	}
 
-	query := r.URL.Query()
-	username := query.Get("createdby")
+	versions, err := ifMatchVersions(r)
+	if err != nil {
+		return err
+	}
 
-	db := db.DB
-	var rows *sql.Rows
-	var err error
+	var newQuiz NewQuiz
+	if err := decodeJSON(w, r, &newQuiz); err != nil {
+		return err
+	}
 
-	dbQuery := `SELECT id, creator, name, grading_type, pass_fail, passing_score, allowed_participations, date_created FROM quiz`
-	if len(username) != 0 {
-		rows, err = db.Query(dbQuery+` WHERE creator=$1`, username)
-	} else {
-		rows, err = db.Query(dbQuery)
+	quiz, err := newQuiz.validate()
+	if err != nil {
+		return NewClientError(err, http.StatusBadRequest, fmt.Sprintf("Invalid form data: %s", err.Error()))
+	}
+	if err := checkCategory("category_id", quiz.CategoryID); err != nil {
+		return err
 	}
+	quiz.Id = quizID
 
+	tx, err := db.DB.Begin()
 	if err != nil {
-		return NewServerError(err, 500, "Error fetching data from database")
+		return NewServerError(err, 500, "Error starting database transaction")
 	}
-	quizes := []Quiz{}
+	defer tx.Rollback()
 
-	for rows.Next() {
-		var quiz Quiz
-		err = rows.Scan(&quiz.Id, &quiz.Creator, &quiz.Name, &quiz.GradingType, &quiz.PassFail, &quiz.PassingScore, &quiz.AllowedParticipations, &quiz.DateCreated)
-		if err != nil {
-			return NewServerError(err, 500, "Error fetching data from database")
-		}
-		quizes = append(quizes, quiz)
+	if err := quiz.update(tx, versions); err != nil {
+		return err
+	}
+	detail := fmt.Sprintf("%s, %d questions", quiz.Name, len(quiz.Questions))
+	if err := addAuditEntry(tx, quizID, username, "quiz.update", detail); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return NewServerError(err, 500, "Quiz not updated in database")
 	}
 
-	mp := map[string]interface{}{"quizes": quizes}
-	js, err := json.Marshal(mp)
+	w.Header().Set("ETag", quizETag(quiz.Version, nil))
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Quiz updated.", "id": quizID, "version": quiz.Version})
+}
+
+func ListOfQuizesHandler(w http.ResponseWriter, r *http.Request) error {
+	filter, err := parseQuizListFilter(r.URL.Query())
 	if err != nil {
-		return NewServerError(err, 500, "Error while parsing response body")
+		return NewClientError(err, http.StatusBadRequest, fmt.Sprintf("Invalid query: %s", err.Error()))
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(js)
-	return nil
+
+	total, err := countQuizzes(&filter)
+	if err != nil {
+		return err
+	}
+	quizes, next, err := listQuizzes(&filter)
+	if err != nil {
+		return err
+	}
+
+	var nextCursor *string
+	if next != nil {
+		encoded := next.encode()
+		nextCursor = &encoded
+	}
+	return writeCachedJSON(w, r, publicCacheControl, map[string]interface{}{"quizes": quizes, "total": total, "next_cursor": nextCursor})
 }
 
 func QuizResultsHandler(w http.ResponseWriter, r *http.Request) error {
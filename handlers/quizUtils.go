@@ -2,14 +2,19 @@ package handlers
 
 import (
 	db "PamQ/database"
-	"errors"
-	"log"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
-	"github.com/mitchellh/mapstructure"
+	"github.com/lib/pq"
 )
 
 type Question struct {
@@ -27,14 +32,19 @@ type Question struct {
 type QuestionType int
 
 const (
-	MultiChoice = iota + 1
+	MultiChoice QuestionType = iota + 1
 	ShortAnswer
 )
 
+// Quiz is a quiz with its questions. Answers are only accepted from OpensAt
+// until ClosesAt, when they are set.
 type Quiz struct {
 	Id                    int        `json:"id"`
 	Creator               string     `json:"creator"`
 	Name                  string     `json:"name"`
+	Description           string     `json:"description" db:"description"`
+	Tags                  []string   `json:"tags"`
+	CategoryID            *int       `json:"category_id" db:"category_id"`
 	Questions             []Question `json:"questions,omitempty"`
 	GradingType           Grading    `json:"grading_type" db:"grading_type"`
 	PassFail              bool       `json:"pass_fail" db:"pass_fail"`
@@ -42,18 +52,31 @@ type Quiz struct {
 	NotFailText           string     `json:"not_fail_text" db:"not_fail_text"`
 	FailText              string     `json:"fail_text" db:"fail_text"`
 	AllowedParticipations int        `json:"allowed_participation" db:"allowed_participation"`
+	Leaderboard           bool       `json:"leaderboard" db:"leaderboard"`
+	LeaderboardShowNames  bool       `json:"leaderboard_show_names" db:"leaderboard_show_names"`
+	OpensAt               *time.Time `json:"opens_at" db:"opens_at"`
+	ClosesAt              *time.Time `json:"closes_at" db:"closes_at"`
+	Version               int        `json:"version" db:"version"`
+	UpdatedAt             JSONTime   `json:"date_updated" db:"updated_at"`
 	DateCreated           JSONTime   `json:"date_created" db:"date_created"`
 }
 
 type NewQuiz struct {
 	Name                  string        `json:"name" db:"name"`
-	NewQuestions          []interface{} `json:"questions"`
+	Description           string        `json:"description" db:"description"`
+	Tags                  []string      `json:"tags"`
+	CategoryID            *int          `json:"category_id" db:"category_id"`
+	Questions             []NewQuestion `json:"questions"`
 	GradingType           Grading       `json:"grading_type" db:"grading_type"`
 	PassFail              bool          `json:"pass_fail" db:"pass_fail"`
 	PassingScore          float64       `json:"passing_score" db:"passing_score"`
 	NotFailText           string        `json:"not_fail_text" db:"not_fail_text"`
 	FailText              string        `json:"fail_text" db:"fail_text"`
 	AllowedParticipations int           `json:"allowed_participation" db:"allowed_participation"`
+	Leaderboard           bool          `json:"leaderboard" db:"leaderboard"`
+	LeaderboardShowNames  bool          `json:"leaderboard_show_names" db:"leaderboard_show_names"`
+	OpensAt               *time.Time    `json:"opens_at" db:"opens_at"`
+	ClosesAt              *time.Time    `json:"closes_at" db:"closes_at"`
 }
 
 type QuizParticipation struct {
@@ -64,6 +87,16 @@ type QuizParticipation struct {
 	Score       float64  `json:"score" db:"score"`
 	PassFail    bool     `json:"pass_fail" db:"pass_fail"`
 	DateCreated JSONTime `json:"date_created" db:"date_created"`
+
+	Answers []ParticipationAnswer `json:"answers,omitempty"`
+}
+
+type ParticipationAnswer struct {
+	ParticipationID int          `json:"-" db:"participation_id"`
+	QuestionID      int          `json:"question_id" db:"question_id"`
+	Answer          string       `json:"answer" db:"answer"`
+	Result          AnswerResult `json:"result" db:"result"`
+	Mark            float64      `json:"mark" db:"mark"`
 }
 
 // type UserAnswer struct {
@@ -140,127 +173,248 @@ func (q *Question) check(userAnswer string) AnswerResult {
 	}
 }
 
-func (q *Question) validate() error {
-	if len(q.Statement) == 0 {
-		return ErrorMissingField("Question statement")
-	}
-	if q.QType == 1 {
-		if len(q.Option1) == 0 {
-			return ErrorMissingField("Option1")
-		}
-		if len(q.Option2) == 0 {
-			return ErrorMissingField("Option2")
-		}
-
-		answerError := errors.New("Please enter a valid number as answer for question.")
-		if len(q.Answer) != 0 {
-			answer, err := strconv.Atoi(q.Answer)
-			if err != nil || answer < 1 || answer > 4 {
-				return answerError
-			}
-		}
-	}
-	return nil
-}
-
 func (q *NewQuiz) validate() (Quiz, error) {
 	var quiz Quiz
+	var errs FieldErrors
 
 	if len(q.Name) == 0 {
-		return quiz, ErrorMissingField("name")
+		errs.add(ErrorMissingField("name"))
 	}
-	if len(q.NewQuestions) == 0 {
-		return quiz, ErrorMissingField("questions")
+	if len(q.Questions) == 0 {
+		errs.add(ErrorMissingField("questions"))
 	}
 
 	if q.GradingType < 1 || q.GradingType > 2 {
-		return quiz, errors.New("Please enter a valid type for Grading Type. (1 if you wrong answers don't have negetive score or 2 otherwise)")
+		errs.add(invalidField("grading_type", "Please enter a valid type for Grading Type. (1 if you wrong answers don't have negetive score or 2 otherwise)"))
 	}
 
 	if q.AllowedParticipations == 0 {
-		return quiz, ErrorMissingField("allowed_participations")
+		errs.add(ErrorMissingField("allowed_participations"))
+	}
+	if q.OpensAt != nil && q.ClosesAt != nil && !q.ClosesAt.After(*q.OpensAt) {
+		errs.add(invalidField("closes_at", "Please enter a closing time after the opening time."))
 	}
+	if q.CategoryID != nil && *q.CategoryID < 1 {
+		errs.add(invalidField("category_id", "Please enter a valid category_id."))
+	}
+	if len(q.Description) > maxDescriptionLength {
+		errs.add(invalidField("description", fmt.Sprintf("Please enter a description of at most %d characters.", maxDescriptionLength)))
+	}
+	tags, err := normalizeTags(q.Tags)
+	errs.add(err)
 
 	quiz.Name = q.Name
-	err := mapstructure.Decode(q, &quiz)
-	if err != nil {
-		log.Println(err)
-		return quiz, err
+	quiz.Description = q.Description
+	quiz.Tags, quiz.CategoryID = tags, q.CategoryID
+	quiz.GradingType = q.GradingType
+	quiz.PassFail, quiz.PassingScore = q.PassFail, q.PassingScore
+	quiz.NotFailText, quiz.FailText = q.NotFailText, q.FailText
+	quiz.AllowedParticipations = q.AllowedParticipations
+	quiz.Leaderboard, quiz.LeaderboardShowNames = q.Leaderboard, q.LeaderboardShowNames
+	quiz.OpensAt, quiz.ClosesAt = q.OpensAt, q.ClosesAt
+
+	for i, question := range q.Questions {
+		if question.Payload == nil {
+			errs.add(ErrorMissingField(fmt.Sprintf("questions[%d].type", i)))
+			continue
+		}
+		if err := question.Payload.validate(); err != nil {
+			errs.add(prefixField(fmt.Sprintf("questions[%d]", i), err))
+			continue
+		}
+		quiz.Questions = append(quiz.Questions, question.Payload.question())
 	}
 
-	for _, value := range q.NewQuestions {
-		qu := value.(map[string]interface{})
+	return quiz, errs.err()
+}
 
-		qTypeError := errors.New("Please enter a valid type for question. (1 for multichoice question or 2 for short answer)")
+func (p *QuizParticipation) addToDB(tx execer) error {
+	row := tx.QueryRow("INSERT INTO quiz_participation (quiz_id, username, result, score, pass_fail) VALUES($1, $2, $3, $4, $5) RETURNING id", p.QuizID, p.Username, p.Result, p.Score, p.PassFail)
+	if err := row.Scan(&p.ID); err != nil {
+		return NewServerError(err, 500, "Quiz participation not saved in database")
+	}
 
-		t, ok := qu["type"].(float64)
-		var questionType int
-		if ok {
-			questionType = int(t)
-		} else {
-			t, ok := qu["type"].(string)
-			if !ok {
-				return quiz, qTypeError
-			}
-			var err error
-			questionType, err = strconv.Atoi(t)
-			if err != nil {
-				return quiz, qTypeError
-			}
+	for i := range p.Answers {
+		a := &p.Answers[i]
+		a.ParticipationID = p.ID
+		if _, err := tx.Exec("INSERT INTO participation_answer (participation_id, question_id, answer, result, mark) VALUES ($1, $2, $3, $4, $5)", a.ParticipationID, a.QuestionID, a.Answer, a.Result, a.Mark); err != nil {
+			return NewServerError(err, 500, "Quiz participation not saved in database")
 		}
+	}
+	return nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func (q *Question) addToDB(db execer) error {
+	row := db.QueryRow("INSERT INTO question (quiz_id, type, statement, option1, option2, option3, option4, answer) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id", q.QuizID, q.QType, q.Statement, q.Option1, q.Option2, q.Option3, q.Option4, q.Answer)
+	if err := row.Scan(&q.Id); err != nil {
+		return NewServerError(err, 500, "Question not saved in database")
+	}
+	return nil
+}
+func (q *Quiz) addToDB(db execer) (int, error) {
+	var quizId int
+	row := db.QueryRow("INSERT INTO quiz (creator, name,  grading_type, pass_fail, passing_score, not_fail_text,fail_text, allowed_participations, leaderboard, leaderboard_show_names, opens_at, closes_at, description, category_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id", q.Creator, q.Name, q.GradingType, q.PassFail, q.PassingScore, q.NotFailText, q.FailText, q.AllowedParticipations, q.Leaderboard, q.LeaderboardShowNames, q.OpensAt, q.ClosesAt, q.Description, q.CategoryID)
+	err := row.Scan(&quizId)
+	if err != nil {
+		return quizId, NewServerError(err, 500, "Quiz not saved in database")
+	}
+	if err := saveTags(db, quizId, q.Tags); err != nil {
+		return quizId, err
+	}
 
-		if questionType != 1 && questionType != 2 {
-			return quiz, qTypeError
+	for _, question := range q.Questions {
+		question.QuizID = quizId
+		err := question.addToDB(db)
+		if err != nil {
+			return quizId, err
 		}
 
-		var question Question
-		mapstructure.Decode(qu, &question)
-		question.QType = QuestionType(questionType)
+	}
+	return quizId, nil
+}
+
+// update saves q as its next version. When versions isn't nil, the quiz is
+// only updated if its current version is one of them.
+func (q *Quiz) update(tx *sql.Tx, versions []int64) error {
+	err := tx.QueryRow("UPDATE quiz SET name=$2, grading_type=$3, pass_fail=$4, passing_score=$5, not_fail_text=$6, fail_text=$7, allowed_participations=$8, leaderboard=$9, leaderboard_show_names=$10, opens_at=$11, closes_at=$12, description=$13, category_id=$14, version=version+1, updated_at=NOW() WHERE id=$1 AND ($15::bigint[] IS NULL OR version = ANY($15)) RETURNING version, updated_at", q.Id, q.Name, q.GradingType, q.PassFail, q.PassingScore, q.NotFailText, q.FailText, q.AllowedParticipations, q.Leaderboard, q.LeaderboardShowNames, q.OpensAt, q.ClosesAt, q.Description, q.CategoryID, pq.Array(versions)).Scan(&q.Version, &q.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return NewCodedError(err, http.StatusPreconditionFailed, CodePreconditionFailed, "The quiz has been changed since you got it. Please get it again.")
+	} else if err != nil {
+		return NewServerError(err, 500, "Quiz not updated in database")
+	}
+	if err := saveTags(tx, q.Id, q.Tags); err != nil {
+		return err
+	}
 
-		if err := question.validate(); err != nil {
-			return quiz, err
+	// Questions are updated in place when their id is given so that answers
+	// recorded against them survive the edit.
+	keep := []int64{}
+	for i := range q.Questions {
+		question := &q.Questions[i]
+		question.QuizID = q.Id
+		if question.Id != 0 {
+			res, err := tx.Exec("UPDATE question SET type=$3, statement=$4, option1=$5, option2=$6, option3=$7, option4=$8, answer=$9 WHERE id=$1 AND quiz_id=$2", question.Id, question.QuizID, question.QType, question.Statement, question.Option1, question.Option2, question.Option3, question.Option4, question.Answer)
+			if err != nil {
+				return NewServerError(err, 500, "Question not saved in database")
+			}
+			if n, err := res.RowsAffected(); err == nil && n == 1 {
+				keep = append(keep, int64(question.Id))
+				continue
+			}
 		}
+		if err := question.addToDB(tx); err != nil {
+			return err
+		}
+		keep = append(keep, int64(question.Id))
+	}
 
-		quiz.Questions = append(quiz.Questions, question)
+	if _, err := tx.Exec("DELETE FROM question WHERE quiz_id=$1 AND NOT (id = ANY($2))", q.Id, pq.Array(keep)); err != nil {
+		return NewServerError(err, 500, "Quiz not updated in database")
 	}
+	return nil
+}
 
-	return quiz, nil
+func (q *Quiz) setCategory(categoryID sql.NullInt64) {
+	if categoryID.Valid {
+		id := int(categoryID.Int64)
+		q.CategoryID = &id
+	}
 }
 
-func (p *QuizParticipation) addToDB() error {
-	db := db.DB
-	if _, err := db.Query("INSERT INTO quiz_participation (quiz_id, username, result, score, pass_fail) VALUES($1, $2, $3, $4, $5)", p.QuizID, p.Username, p.Result, p.Score, p.PassFail); err != nil {
-		return NewServerError(err, 500, "Quiz participation not saved in database")
+// applyResult sets the pass/fail state and result text of p from its score.
+func (q *Quiz) applyResult(p *QuizParticipation) {
+	if q.PassFail && p.Score < q.PassingScore {
+		p.PassFail = false
+		p.Result = q.FailText
+	} else {
+		p.PassFail = true
+		p.Result = q.NotFailText
+	}
+}
+
+// checkOpen refuses answers to q outside of its opening times at now.
+func (q *Quiz) checkOpen(now time.Time) error {
+	if q.OpensAt != nil && now.Before(*q.OpensAt) {
+		return NewClientError(nil, http.StatusForbidden, "This quiz isn't open yet.")
+	}
+	if q.ClosesAt != nil && !now.Before(*q.ClosesAt) {
+		return NewClientError(nil, http.StatusForbidden, "This quiz is closed.")
 	}
 	return nil
 }
 
-func (q *Question) addToDB() error {
-	db := db.DB
-	if _, err := db.Query("INSERT INTO question (quiz_id, type, statement, option1, option2, option3, option4, answer) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)", q.QuizID, q.QType, q.Statement, q.Option1, q.Option2, q.Option3, q.Option4, q.Answer); err != nil {
-		return NewServerError(err, 500, "Question not saved in database")
+func countParticipations(db execer, quizID int, username string) (int, error) {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM quiz_participation WHERE quiz_id=$1 AND username=$2`, quizID, username).Scan(&count); err != nil {
+		return 0, NewServerError(err, 500, "Error fetching data from database")
+	}
+	return count, nil
+}
+
+// checkNewParticipation locks quiz quizID in tx and refuses a participation
+// of username at now outside its opening times or beyond its limit. The lock
+// holds concurrent submissions back until tx ends, so that together they
+// can't exceed the limit.
+func checkNewParticipation(tx execer, quizID int, username string, now time.Time) error {
+	var quiz Quiz
+	err := tx.QueryRow(`SELECT allowed_participations, opens_at, closes_at FROM quiz WHERE id=$1 FOR UPDATE`, quizID).Scan(&quiz.AllowedParticipations, &quiz.OpensAt, &quiz.ClosesAt)
+	if err == sql.ErrNoRows {
+		return NewClientError(err, http.StatusNotFound, "Quiz not found")
+	} else if err != nil {
+		return NewServerError(err, 500, "Error fetching data from database")
+	}
+	if err := quiz.checkOpen(now); err != nil {
+		return err
+	}
+
+	taken, err := countParticipations(tx, quizID, username)
+	if err != nil {
+		return err
+	}
+	if taken >= quiz.AllowedParticipations {
+		return NewClientError(nil, http.StatusBadRequest, "Your participation limit for this quiz has been reached")
 	}
 	return nil
 }
-func (q *Quiz) addToDB() (int, error) {
+
+func getQuiz(quizID int) (Quiz, error) {
+	var quiz Quiz
+
 	db := db.DB
+	var categoryID sql.NullInt64
+	err := db.QueryRow(`SELECT id, creator, name, COALESCE(description, ''), category_id, ARRAY(SELECT tag FROM quiz_tag t WHERE t.quiz_id = quiz.id ORDER BY tag),
+		grading_type, pass_fail, passing_score, not_fail_text, fail_text, allowed_participations, leaderboard, leaderboard_show_names, opens_at, closes_at, version, updated_at, date_created
+		FROM quiz WHERE id=$1`, quizID).Scan(&quiz.Id, &quiz.Creator, &quiz.Name, &quiz.Description, &categoryID, pq.Array(&quiz.Tags),
+		&quiz.GradingType, &quiz.PassFail, &quiz.PassingScore, &quiz.NotFailText, &quiz.FailText, &quiz.AllowedParticipations, &quiz.Leaderboard, &quiz.LeaderboardShowNames, &quiz.OpensAt, &quiz.ClosesAt, &quiz.Version, &quiz.UpdatedAt, &quiz.DateCreated)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return quiz, NewClientError(err, http.StatusNotFound, "Quiz not found")
+		}
+		return quiz, NewServerError(err, 500, "Error fetching data from database")
+	}
+	quiz.setCategory(categoryID)
 
-	var quizId int
-	row := db.QueryRow("INSERT INTO quiz (creator, name,  grading_type, pass_fail, passing_score, not_fail_text,fail_text, allowed_participations) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id", q.Creator, q.Name, q.GradingType, q.PassFail, q.PassingScore, q.NotFailText, q.FailText, q.AllowedParticipations)
-	err := row.Scan(&quizId)
+	rows, err := db.Query(`SELECT id, quiz_id, type, statement, option1, option2, option3, option4, answer FROM question WHERE quiz_id=$1 ORDER BY id`, quizID)
 	if err != nil {
-		return quizId, NewServerError(err, 500, "Quiz not saved in database")
+		return quiz, NewServerError(err, 500, "Error fetching data from database")
 	}
+	defer rows.Close()
 
-	for _, question := range q.Questions {
-		question.QuizID = quizId
-		err := question.addToDB()
+	for rows.Next() {
+		var q Question
+		err = rows.Scan(&q.Id, &q.QuizID, &q.QType, &q.Statement, &q.Option1, &q.Option2, &q.Option3, &q.Option4, &q.Answer)
 		if err != nil {
-			return quizId, nil
+			return quiz, NewServerError(err, 500, "Error fetching data from database")
 		}
-
+		quiz.Questions = append(quiz.Questions, q)
 	}
-	return quizId, nil
+	return quiz, nil
 }
 
 func getQuizIdParam(r *http.Request) (int, error) {
@@ -278,3 +432,288 @@ func getQuizIdParam(r *http.Request) (int, error) {
 	return quizID, nil
 
 }
+
+const (
+	maxDescriptionLength = 10000
+	maxTags              = 10
+	maxTagLength         = 30
+)
+
+var tagPattern = regexp.MustCompile(`^[a-z0-9]+(?:[ _-][a-z0-9]+)*$`)
+
+// normalizeTags lower-cases and trims tags and drops duplicates, so "Go" and
+// " go" are the same tag.
+func normalizeTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if len(tag) == 0 || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTagLength || !tagPattern.MatchString(tag) {
+			return nil, invalidField("tags", fmt.Sprintf("Please enter tags of at most %d letters, digits, spaces, '-' or '_'.", maxTagLength))
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxTags {
+		return nil, invalidField("tags", fmt.Sprintf("Please enter at most %d tags.", maxTags))
+	}
+	return normalized, nil
+}
+
+// saveTags replaces the tags of a quiz.
+func saveTags(db execer, quizID int, tags []string) error {
+	if _, err := db.Exec(`DELETE FROM quiz_tag WHERE quiz_id=$1`, quizID); err != nil {
+		return NewServerError(err, 500, "Tags not saved in database")
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	if _, err := db.Exec(`INSERT INTO quiz_tag (quiz_id, tag) SELECT $1, UNNEST($2::text[])`, quizID, pq.Array(tags)); err != nil {
+		return NewServerError(err, 500, "Tags not saved in database")
+	}
+	return nil
+}
+
+// quizSearchVector must match the expression of quiz_search_idx for the
+// index to be used.
+const quizSearchVector = "to_tsvector('simple', name || ' ' || COALESCE(description, ''))"
+
+// QuizListFilter holds the query parameters of the quiz list.
+type QuizListFilter struct {
+	Creator     string
+	GradingType Grading
+	Tags        []string
+	Category    int
+	Open        *bool
+	Search      string
+	Sort        string
+	Cursor      *quizCursor
+	Limit       int
+}
+
+// quizCursor points after the last quiz of a page: the value of the sort
+// key and the id, which breaks ties.
+type quizCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// QuizListItem is a quiz in the list, without its questions.
+type QuizListItem struct {
+	Quiz
+	Participations int `json:"participations"`
+}
+
+// quizSorts maps each sort to its key column and direction. Keys are
+// compared together with the id, so pages never skip or repeat a quiz.
+var quizSorts = map[string]struct {
+	column string
+	desc   bool
+}{
+	"newest":     {"date_created", true},
+	"most_taken": {"participations", true},
+	"name":       {"name", false},
+}
+
+// encode returns the cursor as sent to clients.
+func (c *quizCursor) encode() string {
+	js, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+// decodeQuizCursor reads a cursor returned by encode.
+func decodeQuizCursor(s string) (*quizCursor, error) {
+	var c quizCursor
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || json.Unmarshal(js, &c) != nil {
+		return nil, invalidField("cursor", "Please enter a cursor returned by a previous page.")
+	}
+	return &c, nil
+}
+
+// parseQuizListFilter reads a QuizListFilter from query, using the defaults
+// for missing parameters.
+func parseQuizListFilter(query url.Values) (QuizListFilter, error) {
+	f := QuizListFilter{Sort: "newest", Limit: defaultPageSize}
+
+	f.Creator = query.Get("creator")
+	if len(f.Creator) == 0 {
+		f.Creator = query.Get("createdby")
+	}
+	if v := query.Get("grading_type"); len(v) != 0 {
+		t, err := strconv.Atoi(v)
+		if err != nil || t < 1 || t > 2 {
+			return f, invalidField("grading_type", "Please enter 1 or 2 for grading_type.")
+		}
+		f.GradingType = Grading(t)
+	}
+	if tags, ok := query["tag"]; ok {
+		var err error
+		if f.Tags, err = normalizeTags(tags); err != nil {
+			return f, err
+		}
+	}
+	if v := query.Get("category"); len(v) != 0 {
+		var err error
+		if f.Category, err = strconv.Atoi(v); err != nil || f.Category < 1 {
+			return f, invalidField("category", "Please enter a valid category id.")
+		}
+	}
+	if v := query.Get("open"); len(v) != 0 {
+		open, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, invalidField("open", "Please enter true or false for open.")
+		}
+		f.Open = &open
+	}
+	f.Search = strings.TrimSpace(query.Get("q"))
+
+	if v := query.Get("sort"); len(v) != 0 {
+		if _, ok := quizSorts[v]; !ok {
+			return f, invalidField("sort", "Please enter a valid sort. (newest, most_taken or name)")
+		}
+		f.Sort = v
+	}
+	if v := query.Get("cursor"); len(v) != 0 {
+		cursor, err := decodeQuizCursor(v)
+		if err != nil {
+			return f, err
+		}
+		if cursor.Sort != f.Sort {
+			return f, invalidField("cursor", "The cursor belongs to another sort.")
+		}
+		f.Cursor = cursor
+	}
+	if v := query.Get("limit"); len(v) != 0 {
+		var err error
+		if f.Limit, err = strconv.Atoi(v); err != nil || f.Limit < 1 || f.Limit > maxPageSize {
+			return f, invalidField("limit", fmt.Sprintf("Please enter a number between 1 and %d for limit.", maxPageSize))
+		}
+	}
+	return f, nil
+}
+
+// where returns the filter conditions, which don't depend on the page.
+func (f *QuizListFilter) where() (string, []interface{}) {
+	conds := []string{"TRUE"}
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(f.Creator) != 0 {
+		conds = append(conds, "creator="+arg(f.Creator))
+	}
+	if f.GradingType != 0 {
+		conds = append(conds, "grading_type="+arg(f.GradingType))
+	}
+	// A quiz must have every tag asked for.
+	for _, tag := range f.Tags {
+		conds = append(conds, "EXISTS (SELECT 1 FROM quiz_tag t WHERE t.quiz_id = quiz.id AND t.tag="+arg(tag)+")")
+	}
+	// Quizzes of subcategories are in their parent category too.
+	if f.Category != 0 {
+		conds = append(conds, `category_id IN (
+			WITH RECURSIVE sub AS (SELECT id FROM category WHERE id=`+arg(f.Category)+`
+				UNION SELECT c.id FROM category c JOIN sub ON c.parent_id = sub.id)
+			SELECT id FROM sub)`)
+	}
+	if f.Open != nil {
+		open := "((opens_at IS NULL OR opens_at <= NOW()) AND (closes_at IS NULL OR closes_at > NOW()))"
+		if !*f.Open {
+			open = "NOT " + open
+		}
+		conds = append(conds, open)
+	}
+	if len(f.Search) != 0 {
+		conds = append(conds, quizSearchVector+" @@ plainto_tsquery('simple', "+arg(f.Search)+")")
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+func countQuizzes(f *QuizListFilter) (int, error) {
+	where, args := f.where()
+	var total int
+	db := db.DB
+	if err := db.QueryRow(`SELECT COUNT(*) FROM quiz WHERE `+where, args...).Scan(&total); err != nil {
+		return 0, NewServerError(err, 500, "Error fetching data from database")
+	}
+	return total, nil
+}
+
+// listQuizzes returns a page of quizzes and the cursor of the next page, if
+// there is one.
+func listQuizzes(f *QuizListFilter) ([]QuizListItem, *quizCursor, error) {
+	where, args := f.where()
+	sort := quizSorts[f.Sort]
+	op, dir := ">", "ASC"
+	if sort.desc {
+		op, dir = "<", "DESC"
+	}
+
+	page := ""
+	if f.Cursor != nil {
+		args = append(args, f.Cursor.Value, f.Cursor.ID)
+		value := fmt.Sprintf("$%d", len(args)-1)
+		switch f.Sort {
+		case "newest":
+			value += "::timestamptz"
+		case "most_taken":
+			value += "::bigint"
+		}
+		page = fmt.Sprintf("WHERE (%s, id) %s (%s, $%d)", sort.column, op, value, len(args))
+	}
+	args = append(args, f.Limit+1)
+
+	db := db.DB
+	rows, err := db.Query(`SELECT * FROM (
+			SELECT id, creator, name, COALESCE(description, '') AS description, category_id, ARRAY(SELECT tag FROM quiz_tag t WHERE t.quiz_id = quiz.id ORDER BY tag) AS tags,
+				grading_type, pass_fail, passing_score, allowed_participations, opens_at, closes_at, version, updated_at, date_created,
+				(SELECT COUNT(*) FROM quiz_participation p WHERE p.quiz_id = quiz.id) AS participations
+			FROM quiz WHERE `+where+`
+		) q `+page+`
+		ORDER BY `+sort.column+` `+dir+`, id `+dir+`
+		LIMIT $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		return nil, nil, NewServerError(err, 500, "Error fetching data from database")
+	}
+	defer rows.Close()
+
+	items := []QuizListItem{}
+	for rows.Next() {
+		var item QuizListItem
+		var categoryID sql.NullInt64
+		err := rows.Scan(&item.Id, &item.Creator, &item.Name, &item.Description, &categoryID, pq.Array(&item.Tags),
+			&item.GradingType, &item.PassFail, &item.PassingScore, &item.AllowedParticipations,
+			&item.OpensAt, &item.ClosesAt, &item.Version, &item.UpdatedAt, &item.DateCreated, &item.Participations)
+		if err != nil {
+			return nil, nil, NewServerError(err, 500, "Error fetching data from database")
+		}
+		item.setCategory(categoryID)
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, NewServerError(err, 500, "Error fetching data from database")
+	}
+
+	if len(items) <= f.Limit {
+		return items, nil, nil
+	}
+	items = items[:f.Limit]
+	last := items[len(items)-1]
+	next := &quizCursor{Sort: f.Sort, ID: last.Id}
+	switch f.Sort {
+	case "newest":
+		next.Value = time.Time(last.DateCreated).Format(time.RFC3339Nano)
+	case "most_taken":
+		next.Value = strconv.Itoa(last.Participations)
+	case "name":
+		next.Value = last.Name
+	}
+	return items, next, nil
+}
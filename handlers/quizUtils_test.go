@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	db "PamQ/database"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQuizCheckOpen(t *testing.T) {
+	opensAt := time.Date(2020, 3, 1, 9, 0, 0, 0, time.UTC)
+	closesAt := opensAt.Add(2 * time.Hour)
+	quiz := Quiz{OpensAt: &opensAt, ClosesAt: &closesAt}
+
+	for _, now := range []time.Time{opensAt, opensAt.Add(time.Hour), closesAt.Add(-time.Second)} {
+		if err := quiz.checkOpen(now); err != nil {
+			t.Errorf("%v: want answers accepted, got %v", now, err)
+		}
+	}
+	for _, now := range []time.Time{opensAt.Add(-time.Second), closesAt, closesAt.Add(time.Hour)} {
+		var herr *HTTPError
+		if err := quiz.checkOpen(now); !errors.As(err, &herr) || herr.Status != http.StatusForbidden {
+			t.Errorf("%v: want answers refused with 403, got %v", now, err)
+		}
+	}
+	if err := (&Quiz{}).checkOpen(opensAt); err != nil {
+		t.Errorf("Want a quiz without opening times always open, got %v", err)
+	}
+}
+
+func parseQuizFilter(t *testing.T, query string) QuizListFilter {
+	t.Helper()
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := parseQuizListFilter(values)
+	if err != nil {
+		t.Fatalf("%s: want no error, got %v", query, err)
+	}
+	return f
+}
+
+func TestQuizListFilterDefaults(t *testing.T) {
+	f := parseQuizFilter(t, "")
+	if f.Sort != "newest" || f.Limit != defaultPageSize || f.Cursor != nil {
+		t.Errorf("Want the first page of the newest quizzes, got %+v", f)
+	}
+	if where, args := f.where(); where != "TRUE" || len(args) != 0 {
+		t.Errorf("Want no conditions, got '%s' %v", where, args)
+	}
+}
+
+func TestQuizListFilterLegacyCreator(t *testing.T) {
+	for _, query := range []string{"creator=sara", "createdby=sara", "creator=sara&createdby=other"} {
+		f := parseQuizFilter(t, query)
+		where, args := f.where()
+		if where != "TRUE AND creator=$1" || !reflect.DeepEqual(args, []interface{}{"sara"}) {
+			t.Errorf("%s: want the quizzes of sara, got '%s' %v", query, where, args)
+		}
+	}
+}
+
+func TestQuizListFilterConditions(t *testing.T) {
+	f := parseQuizFilter(t, "creator=sara&grading_type=2&tag=Math&tag=math&tag=logic&q=+sums+&sort=most_taken&limit=100")
+
+	where, args := f.where()
+	want := "TRUE AND creator=$1 AND grading_type=$2" +
+		" AND EXISTS (SELECT 1 FROM quiz_tag t WHERE t.quiz_id = quiz.id AND t.tag=$3)" +
+		" AND EXISTS (SELECT 1 FROM quiz_tag t WHERE t.quiz_id = quiz.id AND t.tag=$4)" +
+		" AND " + quizSearchVector + " @@ plainto_tsquery('simple', $5)"
+	if where != want {
+		t.Errorf("Want '%s', got '%s'", want, where)
+	}
+	if wantArgs := []interface{}{"sara", Grading(2), "math", "logic", "sums"}; !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("Want arguments %v, got %v", wantArgs, args)
+	}
+	if f.Sort != "most_taken" || f.Limit != 100 {
+		t.Errorf("Want sort most_taken and limit 100, got %s and %d", f.Sort, f.Limit)
+	}
+}
+
+func TestQuizListFilterOpen(t *testing.T) {
+	open := "((opens_at IS NULL OR opens_at <= NOW()) AND (closes_at IS NULL OR closes_at > NOW()))"
+	openNow, notOpen := parseQuizFilter(t, "open=true"), parseQuizFilter(t, "open=false")
+	if where, _ := openNow.where(); where != "TRUE AND "+open {
+		t.Errorf("Want the quizzes open now, got '%s'", where)
+	}
+	if where, _ := notOpen.where(); where != "TRUE AND NOT "+open {
+		t.Errorf("Want the quizzes not open now, got '%s'", where)
+	}
+}
+
+func TestQuizCursorRoundTrip(t *testing.T) {
+	for _, c := range []quizCursor{
+		{Sort: "newest", Value: "2020-01-02T03:04:05.123456Z", ID: 12},
+		{Sort: "most_taken", Value: "0", ID: 1},
+		{Sort: "name", Value: "Ünïcode & \"quotes\"", ID: 99},
+	} {
+		encoded := c.encode()
+		if strings.ContainsAny(encoded, "+/=&") {
+			t.Errorf("Cursor '%s' isn't safe in a query", encoded)
+		}
+		f := parseQuizFilter(t, "sort="+c.Sort+"&cursor="+encoded)
+		if f.Cursor == nil || *f.Cursor != c {
+			t.Errorf("Want %+v, got %+v", c, f.Cursor)
+		}
+	}
+}
+
+func TestQuizListFilterInvalid(t *testing.T) {
+	nameCursor := (&quizCursor{Sort: "name", Value: "Algebra", ID: 7}).encode()
+	fields := map[string]string{
+		"grading_type=3":       "grading_type",
+		"tag=a%2Fb":            "tags",
+		"category=0":           "category",
+		"open=maybe":           "open",
+		"sort=random":          "sort",
+		"cursor=not-a-cursor":  "cursor",
+		"cursor=" + nameCursor: "cursor",
+		"limit=0":              "limit",
+		"limit=101":            "limit",
+		"sort=name&limit=-1":   "limit",
+	}
+
+	for query, field := range fields {
+		values, err := url.ParseQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = parseQuizListFilter(values)
+		var fieldErr FieldError
+		if !errors.As(err, &fieldErr) || fieldErr.Field != field {
+			t.Errorf("%s: want an error on %s, got %v", query, field, err)
+		}
+	}
+}
+
+func TestNormalizeTagsMergesSpellings(t *testing.T) {
+	tags, err := normalizeTags([]string{" Go ", "SQL", "go", "GO", "", "  ", "sql"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"go", "sql"}; !reflect.DeepEqual(tags, want) {
+		t.Errorf("Want %q, got %q", want, tags)
+	}
+	if tags, err := normalizeTags(nil); err != nil || tags == nil || len(tags) != 0 {
+		t.Errorf("Want an empty list without tags, got %q, %v", tags, err)
+	}
+}
+
+func TestNormalizeTagsCharacters(t *testing.T) {
+	valid := []string{"linear algebra", "set-theory", "big_o", "web2"}
+	if tags, err := normalizeTags(valid); err != nil || !reflect.DeepEqual(tags, valid) {
+		t.Errorf("Want %q kept, got %q, %v", valid, tags, err)
+	}
+	for _, tag := range []string{"c++", "a--b", "a/b", "-go", "go_"} {
+		var fieldErr FieldError
+		if _, err := normalizeTags([]string{tag}); !errors.As(err, &fieldErr) || fieldErr.Field != "tags" {
+			t.Errorf("%q: want an error on tags, got %v", tag, err)
+		}
+	}
+}
+
+func TestNormalizeTagsLimits(t *testing.T) {
+	if _, err := normalizeTags([]string{strings.Repeat("a", maxTagLength)}); err != nil {
+		t.Errorf("Want a tag of %d characters accepted, got %v", maxTagLength, err)
+	}
+	if _, err := normalizeTags([]string{strings.Repeat("a", maxTagLength+1)}); err == nil {
+		t.Errorf("Want a tag of %d characters refused", maxTagLength+1)
+	}
+
+	var many, spellings []string
+	for i := 0; i <= maxTags; i++ {
+		many = append(many, strings.Repeat("a", i+1))
+		spellings = append(spellings, strings.Repeat(" ", i)+"Go")
+	}
+	if _, err := normalizeTags(many[:maxTags]); err != nil {
+		t.Errorf("Want %d tags accepted, got %v", maxTags, err)
+	}
+	if _, err := normalizeTags(many); err == nil {
+		t.Errorf("Want %d tags refused", maxTags+1)
+	}
+	if _, err := normalizeTags(spellings); err != nil {
+		t.Errorf("Want spellings of one tag counted once, got %v", err)
+	}
+}
+
+func TestCheckNewParticipationLimit(t *testing.T) {
+	for taken, allowed := range map[int64]bool{1: true, 2: false, 3: false} {
+		d, restore := useStubDB(t, map[string][][]driver.Value{
+			"FOR UPDATE": {{int64(2), nil, nil}},
+			"COUNT(*)":   {{taken}},
+		})
+		err := checkNewParticipation(db.DB, 7, "alice", time.Now())
+		restore()
+
+		if allowed && err != nil {
+			t.Errorf("%d of 2 taken: want another participation allowed, got %v", taken, err)
+		} else if !allowed {
+			wantStatus(t, err, http.StatusBadRequest)
+		}
+		if len(d.statements) != 2 || !strings.Contains(d.statements[0].query, "FOR UPDATE") {
+			t.Errorf("Want the quiz locked before counting, got %v", d.statements)
+		}
+	}
+}
+
+func TestCheckNewParticipationClosed(t *testing.T) {
+	closesAt := time.Now().Add(-time.Minute)
+	d, restore := useStubDB(t, map[string][][]driver.Value{
+		"FOR UPDATE": {{int64(2), nil, closesAt}},
+		"COUNT(*)":   {{int64(0)}},
+	})
+	defer restore()
+
+	wantStatus(t, checkNewParticipation(db.DB, 7, "alice", time.Now()), http.StatusForbidden)
+	if len(d.ran("COUNT(*)")) != 0 {
+		t.Error("Want participations of a closed quiz not counted")
+	}
+}
+
+func TestCheckNewParticipationUnknownQuiz(t *testing.T) {
+	_, restore := useStubDB(t, nil)
+	defer restore()
+	wantStatus(t, checkNewParticipation(db.DB, 7, "alice", time.Now()), http.StatusNotFound)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"PamQ/clientip"
+	"PamQ/config"
+	db "PamQ/database"
+	"PamQ/logging"
+	"PamQ/ratelimit"
+	"PamQ/sessions"
+	"net/http"
+	"time"
+)
+
+var (
+	loginIPLimiter         = newLimiter("login-ip", ratelimit.Rate{Burst: 20, Every: 6 * time.Second})
+	loginUserLimiter       = newLimiter("login-user", ratelimit.Rate{Burst: 10, Every: 30 * time.Second})
+	signupIPLimiter        = newLimiter("signup-ip", ratelimit.Rate{Burst: 5, Every: 12 * time.Minute})
+	submissionIPLimiter    = newLimiter("submission-ip", ratelimit.Rate{Burst: 60, Every: time.Second})
+	submissionUserLimiter  = newLimiter("submission-user", ratelimit.Rate{Burst: 10, Every: 6 * time.Second})
+	passwordResetIPLimiter = newLimiter("password-reset-ip", ratelimit.Rate{Burst: 5, Every: 12 * time.Minute})
+)
+
+// newLimiter keeps limits in memory unless PAMQ_RATE_LIMIT_BACKEND is
+// "postgres", which shares them between servers.
+func newLimiter(name string, rate ratelimit.Rate) ratelimit.Limiter {
+	if config.String("RATE_LIMIT_BACKEND", "memory") == "postgres" {
+		return ratelimit.NewPostgresLimiter(db.DB, name, rate)
+	}
+	return ratelimit.NewMemoryLimiter(rate)
+}
+
+func clientIP(r *http.Request) string {
+	return clientip.FromRequest(r)
+}
+
+// allow checks limiter for key. Errors of the limiter itself are logged and
+// let the request through rather than locking everyone out.
+func allow(limiter ratelimit.Limiter, key string) error {
+	ok, retryAfter, err := limiter.Allow(key)
+	if err != nil {
+		logging.Default.Error("rate limiter error", "cause", causes(err))
+		return nil
+	}
+	if !ok {
+		return NewTooManyRequestsError(retryAfter, "Too many requests, please try again later.")
+	}
+	return nil
+}
+
+func limitBy(limiter ratelimit.Limiter, key func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := allow(limiter, key(r)); err != nil {
+				reject(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// userOrIP keys logged in users by username and anyone else by IP. Token
+// requests are keyed by their user before any scope is granted.
+func userOrIP(r *http.Request) string {
+	if username, _ := sessions.GetUsername(r); len(username) != 0 {
+		return "user:" + username
+	}
+	return "ip:" + clientIP(r)
+}
+
+var (
+	LimitLogin         = limitBy(loginIPLimiter, clientIP)
+	LimitSignup        = limitBy(signupIPLimiter, clientIP)
+	LimitPasswordReset = limitBy(passwordResetIPLimiter, clientIP)
+)
+
+func LimitSubmission(next http.Handler) http.Handler {
+	return limitBy(submissionIPLimiter, clientIP)(limitBy(submissionUserLimiter, userOrIP)(next))
+}
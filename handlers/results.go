@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	db "PamQ/database"
+	"PamQ/xlsx"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func QuizParticipationsHandler(w http.ResponseWriter, r *http.Request) error {
+	quizID, err := getQuizIdParam(r)
+	if err != nil {
+		return err
+	}
+	if _, err := requireQuizPermission(r, quizID, ViewResults); err != nil {
+		return err
+	}
+
+	filter, err := parseParticipationFilter(r.URL.Query())
+	if err != nil {
+		return NewClientError(err, http.StatusBadRequest, fmt.Sprintf("Invalid query: %s", err.Error()))
+	}
+
+	participations, err := getFilteredParticipations(quizID, filter)
+	if err != nil {
+		return err
+	}
+	stats, err := getParticipationStats(quizID, filter)
+	if err != nil {
+		return err
+	}
+
+	mp := map[string]interface{}{
+		"participations": participations,
+		"page":           filter.Page,
+		"per_page":       filter.PerPage,
+		"total":          stats.Count,
+		"stats":          stats}
+	return writeJSON(w, http.StatusOK, mp)
+}
+
+// GradeParticipationHandler lets a grader override the score of a
+// participation; pass/fail and the result text follow the quiz settings.
+func GradeParticipationHandler(w http.ResponseWriter, r *http.Request) error {
+	quizID, err := getQuizIdParam(r)
+	if err != nil {
+		return err
+	}
+	participationID, err := strconv.Atoi(mux.Vars(r)["participationID"])
+	if err != nil {
+		return NewClientError(err, http.StatusNotFound, "Page not found")
+	}
+	username, err := requireQuizPermission(r, quizID, GradeParticipations)
+	if err != nil {
+		return err
+	}
+
+	var grade ManualGrade
+	if err := decodeJSON(w, r, &grade); err != nil {
+		return err
+	}
+	if err := grade.validate(); err != nil {
+		return NewClientError(err, http.StatusBadRequest, fmt.Sprintf("Invalid form data: %s", err.Error()))
+	}
+
+	quiz, err := getQuiz(quizID)
+	if err != nil {
+		return err
+	}
+	participation := QuizParticipation{
+		ID:     participationID,
+		QuizID: quizID,
+		Score:  *grade.Score}
+	quiz.applyResult(&participation)
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return NewServerError(err, 500, "Error starting database transaction")
+	}
+	defer tx.Rollback()
+
+	if err := participation.updateGrade(tx); err != nil {
+		return err
+	}
+	detail := fmt.Sprintf("participation %d scored %.2f", participationID, participation.Score)
+	if err := addAuditEntry(tx, quizID, username, "participation.grade", detail); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return NewServerError(err, 500, "Quiz participation not saved in database")
+	}
+
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Participation graded.", "result": participation.Result, "score": participation.Score, "pass": participation.PassFail})
+}
+
+// QuizAnalysisHandler reports item analysis statistics computed from the
+// recorded answers of a quiz.
+func QuizAnalysisHandler(w http.ResponseWriter, r *http.Request) error {
+	quizID, err := getQuizIdParam(r)
+	if err != nil {
+		return err
+	}
+	if _, err := requireQuizPermission(r, quizID, ViewResults); err != nil {
+		return err
+	}
+
+	quiz, err := getQuiz(quizID)
+	if err != nil {
+		return err
+	}
+	answers, err := getAnswersByParticipation(quizID)
+	if err != nil {
+		return err
+	}
+
+	return writeJSON(w, http.StatusOK, analyzeQuiz(quiz, answers))
+}
+
+// ExportParticipationsHandler streams the participations of a quiz as CSV or
+// XLSX. It accepts the same filters as QuizParticipationsHandler; with
+// ?questions=true every question gets an answer and a mark column.
+func ExportParticipationsHandler(w http.ResponseWriter, r *http.Request) error {
+	quizID, err := getQuizIdParam(r)
+	if err != nil {
+		return err
+	}
+	if _, err := requireQuizPermission(r, quizID, ViewResults); err != nil {
+		return err
+	}
+
+	query := r.URL.Query()
+	filter, err := parseParticipationFilter(query)
+	if err != nil {
+		return NewClientError(err, http.StatusBadRequest, fmt.Sprintf("Invalid query: %s", err.Error()))
+	}
+	if len(query.Get("sort")) == 0 && len(query.Get("order")) == 0 {
+		filter.Desc = false
+	}
+	withQuestions, _ := strconv.ParseBool(query.Get("questions"))
+
+	quiz, err := getQuiz(quizID)
+	if err != nil {
+		return err
+	}
+
+	header := []interface{}{"participation_id", "username", "date", "score", "pass", "result"}
+	if withQuestions {
+		for i := range quiz.Questions {
+			header = append(header, fmt.Sprintf("q%d_answer", i+1), fmt.Sprintf("q%d_mark", i+1))
+		}
+	}
+
+	format := query.Get("format")
+	if len(format) == 0 {
+		format = "csv"
+	}
+	if format != "csv" && format != "xlsx" {
+		return NewClientError(nil, http.StatusBadRequest, "Please enter a valid format. (csv or xlsx)")
+	}
+
+	// The query runs before anything is written, so that its failure is
+	// still reported as an error rather than as an empty download.
+	rows, err := queryParticipationsForExport(quizID, filter)
+	if err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("quiz-%d-results.%s", quizID, format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	var out rowWriter
+	if format == "xlsx" {
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		xw, err := xlsx.NewWriter(w, "Results")
+		if err != nil {
+			rows.Close()
+			requestLogger(r).Error("export failed", "quiz_id", quizID, "cause", causes(err))
+			return nil
+		}
+		out = xw
+	} else {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		out = newCSVRowWriter(w)
+	}
+
+	// From here on the response has started, so failures are only logged.
+	if err := out.WriteRow(header...); err != nil {
+		rows.Close()
+		requestLogger(r).Error("export failed", "quiz_id", quizID, "cause", causes(err))
+		return nil
+	}
+	count := 0
+	err = eachExportRow(rows, func(p exportRow) error {
+		row := []interface{}{p.ID, p.Username, time.Time(p.DateCreated), p.Score, p.PassFail, p.Result}
+		if withQuestions {
+			for _, q := range quiz.Questions {
+				id := strconv.Itoa(q.Id)
+				if mark, ok := p.Marks[id]; ok {
+					row = append(row, p.Answers[id], mark)
+				} else {
+					row = append(row, nil, nil)
+				}
+			}
+		}
+		count++
+		if count%100 == 0 {
+			if err := out.Flush(); err != nil {
+				return err
+			}
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+		return out.WriteRow(row...)
+	})
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		requestLogger(r).Error("export failed", "quiz_id", quizID, "cause", causes(err))
+	}
+	return nil
+}
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	db "PamQ/database"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ManualGrade struct {
+	Score *float64 `json:"score"`
+}
+
+func (g *ManualGrade) validate() error {
+	if g.Score == nil {
+		return ErrorMissingField("score")
+	}
+	if *g.Score < 0 || *g.Score > 100 {
+		return invalidField("score", "Please enter a score between 0 and 100.")
+	}
+	return nil
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	histogramBins   = 10
+)
+
+// ParticipationFilter holds the query parameters accepted by the creator's
+// participations listing.
+type ParticipationFilter struct {
+	From     *time.Time
+	To       *time.Time
+	Pass     *bool
+	Username string
+	Sort     string
+	Desc     bool
+	Page     int
+	PerPage  int
+}
+
+type HistogramBin struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
+
+type ParticipationStats struct {
+	Count     int            `json:"count"`
+	Mean      float64        `json:"mean"`
+	Median    float64        `json:"median"`
+	PassRate  float64        `json:"pass_rate"`
+	Histogram []HistogramBin `json:"histogram"`
+}
+
+var participationSortColumns = map[string]string{
+	"date":     "date_created",
+	"score":    "score",
+	"username": "username",
+}
+
+func parseDateParam(name, value string) (*time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, invalidField(name, fmt.Sprintf("Please enter a valid date for %s. (YYYY-MM-DD or RFC 3339)", name))
+	}
+	return &t, nil
+}
+
+// parseParticipationFilter reads a ParticipationFilter from query, using
+// the defaults for missing parameters.
+func parseParticipationFilter(query url.Values) (ParticipationFilter, error) {
+	f := ParticipationFilter{Sort: "date", Desc: true, Page: 1, PerPage: defaultPageSize}
+
+	var err error
+	if v := query.Get("from"); len(v) != 0 {
+		if f.From, err = parseDateParam("from", v); err != nil {
+			return f, err
+		}
+	}
+	if v := query.Get("to"); len(v) != 0 {
+		if f.To, err = parseDateParam("to", v); err != nil {
+			return f, err
+		}
+		if len(v) == len("2006-01-02") {
+			// A bare date includes the whole day.
+			end := f.To.AddDate(0, 0, 1)
+			f.To = &end
+		}
+	}
+	if v := query.Get("pass"); len(v) != 0 {
+		pass, err := strconv.ParseBool(v)
+		if err != nil {
+			return f, invalidField("pass", "Please enter true or false for pass.")
+		}
+		f.Pass = &pass
+	}
+	f.Username = query.Get("user")
+
+	if v := query.Get("sort"); len(v) != 0 {
+		if _, ok := participationSortColumns[v]; !ok {
+			return f, invalidField("sort", "Please enter a valid sort. (date, score or username)")
+		}
+		f.Sort = v
+		f.Desc = v != "username"
+	}
+	switch query.Get("order") {
+	case "":
+	case "asc":
+		f.Desc = false
+	case "desc":
+		f.Desc = true
+	default:
+		return f, invalidField("order", "Please enter a valid order. (asc or desc)")
+	}
+
+	if v := query.Get("page"); len(v) != 0 {
+		if f.Page, err = strconv.Atoi(v); err != nil || f.Page < 1 {
+			return f, invalidField("page", "Please enter a positive number for page.")
+		}
+	}
+	if v := query.Get("per_page"); len(v) != 0 {
+		if f.PerPage, err = strconv.Atoi(v); err != nil || f.PerPage < 1 || f.PerPage > maxPageSize {
+			return f, invalidField("per_page", fmt.Sprintf("Please enter a number between 1 and %d for per_page.", maxPageSize))
+		}
+	}
+	return f, nil
+}
+
+// where builds the WHERE clause shared by the listing and its statistics.
+func (f *ParticipationFilter) where(quizID int) (string, []interface{}) {
+	conds := []string{"quiz_id=$1"}
+	args := []interface{}{quizID}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.From != nil {
+		add("date_created >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("date_created < $%d", *f.To)
+	}
+	if f.Pass != nil {
+		add("pass_fail = $%d", *f.Pass)
+	}
+	if len(f.Username) != 0 {
+		add("username = $%d", f.Username)
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+// orderBy builds the ORDER BY clause for the sort of f.
+func (f *ParticipationFilter) orderBy() string {
+	dir := "ASC"
+	if f.Desc {
+		dir = "DESC"
+	}
+	return fmt.Sprintf(" ORDER BY %s %s NULLS LAST, id %s", participationSortColumns[f.Sort], dir, dir)
+}
+
+func getFilteredParticipations(quizID int, f ParticipationFilter) ([]QuizParticipation, error) {
+	where, args := f.where(quizID)
+	args = append(args, f.PerPage, (f.Page-1)*f.PerPage)
+	query := `SELECT id, quiz_id, username, result, score, pass_fail, date_created FROM quiz_participation` +
+		where + f.orderBy() + fmt.Sprintf(" LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	db := db.DB
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, NewServerError(err, 500, "Error fetching data from database")
+	}
+	defer rows.Close()
+	return scanParticipations(rows)
+}
+
+func getParticipationStats(quizID int, f ParticipationFilter) (ParticipationStats, error) {
+	stats := ParticipationStats{Histogram: make([]HistogramBin, histogramBins)}
+	for i := range stats.Histogram {
+		stats.Histogram[i].From = float64(i * 100 / histogramBins)
+		stats.Histogram[i].To = float64((i + 1) * 100 / histogramBins)
+	}
+
+	where, args := f.where(quizID)
+	db := db.DB
+
+	var mean, median, passRate sql.NullFloat64
+	err := db.QueryRow(`SELECT COUNT(*), AVG(score), PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY score), AVG(CASE WHEN pass_fail THEN 1.0 ELSE 0.0 END)
+		FROM quiz_participation`+where, args...).Scan(&stats.Count, &mean, &median, &passRate)
+	if err != nil {
+		return stats, NewServerError(err, 500, "Error fetching data from database")
+	}
+	stats.Mean, stats.Median, stats.PassRate = mean.Float64, median.Float64, passRate.Float64
+
+	// Scores below 0 (negative marking) or of exactly 100 fall into the
+	// first and last bins respectively.
+	rows, err := db.Query(fmt.Sprintf(`SELECT LEAST(GREATEST(WIDTH_BUCKET(score, 0, 100, %d), 1), %d) AS bin, COUNT(*)
+		FROM quiz_participation%s AND score IS NOT NULL GROUP BY bin`, histogramBins, histogramBins, where), args...)
+	if err != nil {
+		return stats, NewServerError(err, 500, "Error fetching data from database")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bin, count int
+		if err := rows.Scan(&bin, &count); err != nil {
+			return stats, NewServerError(err, 500, "Error fetching data from database")
+		}
+		stats.Histogram[bin-1].Count = count
+	}
+	return stats, nil
+}
+
+func scanParticipations(rows *sql.Rows) ([]QuizParticipation, error) {
+	participations := []QuizParticipation{}
+	for rows.Next() {
+		var qp QuizParticipation
+		var result sql.NullString
+		var score sql.NullFloat64
+		var passFail sql.NullBool
+		if err := rows.Scan(&qp.ID, &qp.QuizID, &qp.Username, &result, &score, &passFail, &qp.DateCreated); err != nil {
+			return nil, NewServerError(err, 500, "Error fetching data from database")
+		}
+		qp.Result, qp.Score, qp.PassFail = result.String, score.Float64, passFail.Bool
+		participations = append(participations, qp)
+	}
+	return participations, nil
+}
+
+func (p *QuizParticipation) updateGrade(tx *sql.Tx) error {
+	res, err := tx.Exec(`UPDATE quiz_participation SET score=$3, pass_fail=$4, result=$5 WHERE id=$1 AND quiz_id=$2`, p.ID, p.QuizID, p.Score, p.PassFail, p.Result)
+	if err != nil {
+		return NewServerError(err, 500, "Quiz participation not saved in database")
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return NewClientError(nil, http.StatusNotFound, "Participation not found")
+	}
+	return nil
+}
+
+// exportRow is one participation with its recorded answers keyed by
+// question id.
+type exportRow struct {
+	QuizParticipation
+	Answers map[string]string
+	Marks   map[string]float64
+}
+
+// queryParticipationsForExport selects the participations matching f,
+// without pagination, for eachExportRow.
+func queryParticipationsForExport(quizID int, f ParticipationFilter) (*sql.Rows, error) {
+	where, args := f.where(quizID)
+	query := `SELECT p.id, p.quiz_id, p.username, p.result, p.score, p.pass_fail, p.date_created,
+		COALESCE(json_object_agg(a.question_id, a.answer) FILTER (WHERE a.question_id IS NOT NULL), '{}'),
+		COALESCE(json_object_agg(a.question_id, a.mark) FILTER (WHERE a.question_id IS NOT NULL), '{}')
+		FROM (SELECT * FROM quiz_participation` + where + `) p
+		LEFT JOIN participation_answer a ON a.participation_id = p.id
+		GROUP BY p.id, p.quiz_id, p.username, p.result, p.score, p.pass_fail, p.date_created` + f.orderBy()
+
+	db := db.DB
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, NewServerError(err, 500, "Error fetching data from database")
+	}
+	return rows, nil
+}
+
+// eachExportRow streams the rows of queryParticipationsForExport, calling fn
+// for each of them, and closes rows.
+func eachExportRow(rows *sql.Rows, fn func(exportRow) error) error {
+	defer rows.Close()
+	for rows.Next() {
+		var row exportRow
+		var result sql.NullString
+		var score sql.NullFloat64
+		var passFail sql.NullBool
+		var answers, marks []byte
+		if err := rows.Scan(&row.ID, &row.QuizID, &row.Username, &result, &score, &passFail, &row.DateCreated, &answers, &marks); err != nil {
+			return NewServerError(err, 500, "Error fetching data from database")
+		}
+		row.Result, row.Score, row.PassFail = result.String, score.Float64, passFail.Bool
+		if err := json.Unmarshal(answers, &row.Answers); err != nil {
+			return NewServerError(err, 500, "Error fetching data from database")
+		}
+		if err := json.Unmarshal(marks, &row.Marks); err != nil {
+			return NewServerError(err, 500, "Error fetching data from database")
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+type rowWriter interface {
+	WriteRow(cells ...interface{}) error
+	Flush() error
+	Close() error
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func newCSVRowWriter(w io.Writer) *csvRowWriter {
+	return &csvRowWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvRowWriter) WriteRow(cells ...interface{}) error {
+	record := make([]string, len(cells))
+	for i, cell := range cells {
+		switch v := cell.(type) {
+		case nil:
+		case float64:
+			record[i] = strconv.FormatFloat(v, 'f', -1, 64)
+		case time.Time:
+			record[i] = v.Format(time.RFC3339)
+		default:
+			record[i] = escapeFormula(fmt.Sprint(v))
+		}
+	}
+	return c.w.Write(record)
+}
+
+// escapeFormula keeps spreadsheets from evaluating text cells, which hold
+// usernames and answers of participants, as formulas.
+func escapeFormula(s string) string {
+	if len(s) != 0 && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (c *csvRowWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvRowWriter) Close() error {
+	return c.Flush()
+}
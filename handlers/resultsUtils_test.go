@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestManualGradeValidate(t *testing.T) {
+	score := func(v float64) *float64 { return &v }
+
+	for _, v := range []float64{0, 42.5, 100} {
+		if err := (&ManualGrade{Score: score(v)}).validate(); err != nil {
+			t.Errorf("Score %v: want no error, got %v", v, err)
+		}
+	}
+
+	var missing ErrorMissingField
+	if err := (&ManualGrade{}).validate(); !errors.As(err, &missing) || missing != "score" {
+		t.Errorf("Want score to be required, got %v", err)
+	}
+	for _, v := range []float64{-0.5, -100, 100.5} {
+		var fieldErr FieldError
+		if err := (&ManualGrade{Score: score(v)}).validate(); !errors.As(err, &fieldErr) || fieldErr.Field != "score" {
+			t.Errorf("Score %v: want an error on score, got %v", v, err)
+		}
+	}
+}
+
+func parseFilter(t *testing.T, query string) ParticipationFilter {
+	t.Helper()
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := parseParticipationFilter(values)
+	if err != nil {
+		t.Fatalf("%s: want no error, got %v", query, err)
+	}
+	return f
+}
+
+func TestParticipationFilterDates(t *testing.T) {
+	f := parseFilter(t, "from=2020-01-01&to=2020-01-31")
+	where, args := f.where(7)
+	if where != " WHERE quiz_id=$1 AND date_created >= $2 AND date_created < $3" {
+		t.Errorf("Unexpected clause '%s'", where)
+	}
+	from, to := args[1].(time.Time), args[2].(time.Time)
+	if !from.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Want the range to start on January 1st, got %v", from)
+	}
+	// A bare end date includes the whole day.
+	if !to.Equal(time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Want the range to end after January 31st, got %v", to)
+	}
+
+	f = parseFilter(t, "to=2020-01-31T12:00:00Z")
+	if _, args := f.where(7); !args[1].(time.Time).Equal(time.Date(2020, 1, 31, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Want an RFC 3339 end to be exact, got %v", args[1])
+	}
+}
+
+func TestParticipationFilterOutcome(t *testing.T) {
+	f := parseFilter(t, "pass=false&user=sara")
+	where, args := f.where(7)
+	if where != " WHERE quiz_id=$1 AND pass_fail = $2 AND username = $3" {
+		t.Errorf("Unexpected clause '%s'", where)
+	}
+	if !reflect.DeepEqual(args, []interface{}{7, false, "sara"}) {
+		t.Errorf("Unexpected arguments %v", args)
+	}
+}
+
+func TestParticipationFilterOrder(t *testing.T) {
+	orders := map[string]string{
+		"":                         " ORDER BY date_created DESC NULLS LAST, id DESC",
+		"sort=score":               " ORDER BY score DESC NULLS LAST, id DESC",
+		"sort=username":            " ORDER BY username ASC NULLS LAST, id ASC",
+		"sort=date&order=asc":      " ORDER BY date_created ASC NULLS LAST, id ASC",
+		"sort=username&order=desc": " ORDER BY username DESC NULLS LAST, id DESC",
+	}
+	for query, want := range orders {
+		f := parseFilter(t, query)
+		if got := f.orderBy(); got != want {
+			t.Errorf("%s: want '%s', got '%s'", query, want, got)
+		}
+	}
+}
+
+func TestParticipationFilterPaging(t *testing.T) {
+	f := parseFilter(t, "")
+	if f.Page != 1 || f.PerPage != defaultPageSize {
+		t.Errorf("Want the first page of %d, got page %d of %d", defaultPageSize, f.Page, f.PerPage)
+	}
+	f = parseFilter(t, "page=3&per_page=100")
+	if f.Page != 3 || f.PerPage != 100 {
+		t.Errorf("Want page 3 of 100, got page %d of %d", f.Page, f.PerPage)
+	}
+}
+
+func TestParticipationFilterInvalid(t *testing.T) {
+	invalid := map[string]string{
+		"from=yesterday": "from",
+		"to=2020-13-01":  "to",
+		"pass=maybe":     "pass",
+		"sort=id":        "sort",
+		"order=up":       "order",
+		"page=0":         "page",
+		"per_page=101":   "per_page",
+	}
+	for query, field := range invalid {
+		values, _ := url.ParseQuery(query)
+		_, err := parseParticipationFilter(values)
+		var fieldErr FieldError
+		if !errors.As(err, &fieldErr) || fieldErr.Field != field {
+			t.Errorf("%s: want an error on '%s', got %v", query, field, err)
+		}
+	}
+}
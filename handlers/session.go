@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"PamQ/sessions"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func ListSessionsHandler(w http.ResponseWriter, r *http.Request) error {
+	if !sessions.IsLoggedIn(r) {
+		return NewClientError(nil, http.StatusUnauthorized, "Please login first")
+	}
+	username, ok := sessions.GetUsername(r)
+	if !ok {
+		return NewServerError(nil, 500, "Error getting username from session")
+	}
+
+	list, err := sessions.List(r, username)
+	if err != nil {
+		return NewServerError(err, 500, "Error fetching data from database")
+	}
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"sessions": list})
+}
+
+func RevokeSessionHandler(w http.ResponseWriter, r *http.Request) error {
+	if !sessions.IsLoggedIn(r) {
+		return NewClientError(nil, http.StatusUnauthorized, "Please login first")
+	}
+	username, ok := sessions.GetUsername(r)
+	if !ok {
+		return NewServerError(nil, 500, "Error getting username from session")
+	}
+
+	err := sessions.Revoke(username, mux.Vars(r)["sessionID"])
+	if err == sessions.ErrNotFound {
+		return NewClientError(err, http.StatusNotFound, "Session not found")
+	} else if err != nil {
+		return NewServerError(err, 500, "Error deleting session from database")
+	}
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Session revoked."})
+}
+
+func LogoutAllHandler(w http.ResponseWriter, r *http.Request) error {
+	if !sessions.IsLoggedIn(r) {
+		return NewClientError(nil, http.StatusUnauthorized, "Please login first")
+	}
+	username, ok := sessions.GetUsername(r)
+	if !ok {
+		return NewServerError(nil, 500, "Error getting username from session")
+	}
+
+	if err := sessions.LogoutAll(w, username); err != nil {
+		return NewServerError(err, 500, "Sessions logout error")
+	}
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Logged out of all devices. API tokens stay valid until revoked."})
+}
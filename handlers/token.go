@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"PamQ/apitokens"
+	"PamQ/sessions"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type NewToken struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+func (t *NewToken) validate() error {
+	var errs FieldErrors
+	if len(t.Name) == 0 {
+		errs.add(ErrorMissingField("name"))
+	} else if len(t.Name) > 100 {
+		errs.add(invalidField("name", "Please enter a name of at most 100 characters."))
+	}
+	if len(t.Scopes) == 0 {
+		errs.add(ErrorMissingField("scopes"))
+	}
+	for _, scope := range t.Scopes {
+		if !apitokens.ValidScope(scope) {
+			errs.add(invalidField("scopes", fmt.Sprintf("Please enter valid scopes. (%v)", apitokens.Scopes)))
+			break
+		}
+	}
+	if t.ExpiresInDays < 0 {
+		errs.add(invalidField("expires_in_days", "Please enter a positive number for expires_in_days, or 0 for a token that doesn't expire."))
+	}
+	return errs.err()
+}
+
+// sessionUsername returns the user of a cookie session. Tokens can't be used
+// to manage tokens.
+func sessionUsername(r *http.Request) (string, error) {
+	if !sessions.IsLoggedIn(r) {
+		return "", NewClientError(nil, http.StatusUnauthorized, "Please login first")
+	}
+	username, ok := sessions.GetUsername(r)
+	if !ok {
+		return "", NewServerError(nil, 500, "Error getting username from session")
+	}
+	return username, nil
+}
+
+func TokensHandler(w http.ResponseWriter, r *http.Request) error {
+	username, err := sessionUsername(r)
+	if err != nil {
+		return err
+	}
+
+	if r.Method == http.MethodGet {
+		tokens, err := apitokens.List(username)
+		if err != nil {
+			return NewServerError(err, 500, "Error fetching data from database")
+		}
+		return writeJSON(w, http.StatusOK, map[string]interface{}{"tokens": tokens})
+	}
+
+	var newToken NewToken
+	if err := decodeJSON(w, r, &newToken); err != nil {
+		return err
+	}
+	if err := newToken.validate(); err != nil {
+		return NewClientError(err, http.StatusBadRequest, fmt.Sprintf("Invalid form data: %s", err.Error()))
+	}
+
+	expiresAt := apitokens.Expiry(time.Now(), newToken.ExpiresInDays)
+	secret, token, err := apitokens.Create(username, newToken.Name, newToken.Scopes, expiresAt)
+	if err != nil {
+		return NewServerError(err, 500, "Token not saved in database")
+	}
+
+	mp := map[string]interface{}{"message": "Token created. It won't be shown again.", "token": secret, "details": token}
+	return writeJSON(w, http.StatusCreated, mp)
+}
+
+func RevokeTokenHandler(w http.ResponseWriter, r *http.Request) error {
+	username, err := sessionUsername(r)
+	if err != nil {
+		return err
+	}
+	tokenID, err := strconv.Atoi(mux.Vars(r)["tokenID"])
+	if err != nil {
+		return NewClientError(err, http.StatusNotFound, "Token not found")
+	}
+
+	err = apitokens.Revoke(username, tokenID)
+	if err == apitokens.ErrNotFound {
+		return NewClientError(err, http.StatusNotFound, "Token not found")
+	} else if err != nil {
+		return NewServerError(err, 500, "Error deleting token from database")
+	}
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Token revoked."})
+}
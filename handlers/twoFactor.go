@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"PamQ/sessions"
+	"PamQ/totp"
+	"fmt"
+	"net/http"
+)
+
+// LoginTwoFactorHandler is the second step of the login of users having
+// two-factor authentication enabled.
+func LoginTwoFactorHandler(w http.ResponseWriter, r *http.Request) error {
+	var req TwoFactorRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+	if err := req.validate(); err != nil {
+		return NewClientError(err, http.StatusBadRequest, fmt.Sprintf("Invalid form data: %s", err.Error()))
+	}
+
+	user, err := pendingTwoFactorUser(r)
+	if err != nil {
+		return err
+	}
+	if err := allow(loginUserLimiter, user.Username); err != nil {
+		return err
+	}
+	lock, err := checkLoginLock(r, user.Username)
+	if err != nil {
+		return err
+	}
+
+	if err := checkSecondFactor(user, &req); err != nil {
+		if httpErr, ok := err.(*HTTPError); ok && httpErr.Status == http.StatusUnauthorized {
+			loginFailures.Inc("two_factor")
+			if err := recordLoginFailure(user.Username, clientIP(r)); err != nil {
+				requestLogger(r).Error("error recording failed login", "user", user.Username, "cause", causes(err))
+			}
+		}
+		return err
+	}
+	if lock.Failures > 0 {
+		if err := resetLoginFailures(user.Username, clientIP(r)); err != nil {
+			requestLogger(r).Error("error resetting failed logins", "user", user.Username, "cause", causes(err))
+		}
+	}
+
+	setTwoFactorCookie(w, "")
+	if err := sessions.Login(w, r, user.Username); err != nil {
+		return NewServerError(err, 500, "Sessions login error")
+	}
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Login succesful.", "username": user.Username})
+}
+
+// TwoFactorSetupHandler creates a new TOTP secret for the logged in user to
+// add to their authenticator app.
+func TwoFactorSetupHandler(w http.ResponseWriter, r *http.Request) error {
+	user, err := loggedInUser(r)
+	if err != nil {
+		return err
+	}
+
+	var req TwoFactorRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+	if err := confirmUser(r, user, req.Password); err != nil {
+		return err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return NewServerError(err, 500, "Error generating two-factor secret")
+	}
+	if err := setTOTPSecret(user.Username, secret); err != nil {
+		return err
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	return writeJSON(w, http.StatusOK, map[string]interface{}{
+		"secret":           secret,
+		"provisioning_uri": totp.ProvisioningURI(totpIssuer, user.Username, secret),
+	})
+}
+
+// TwoFactorEnableHandler confirms the setup with a code from the app and
+// returns the recovery codes, which are only shown once.
+func TwoFactorEnableHandler(w http.ResponseWriter, r *http.Request) error {
+	user, err := loggedInUser(r)
+	if err != nil {
+		return err
+	}
+	if user.TOTPEnabled {
+		return NewClientError(nil, http.StatusConflict, "Two-factor authentication is already enabled")
+	}
+	if len(user.TOTPSecret) == 0 {
+		return NewClientError(nil, http.StatusBadRequest, "Please start the two-factor setup first.")
+	}
+
+	var req TwoFactorRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+	if len(req.Code) == 0 {
+		return NewClientError(ErrorMissingField("code"), http.StatusBadRequest, "Invalid form data: code is required.")
+	}
+	ok, err := useTOTPCode(user, req.Code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NewClientError(nil, http.StatusBadRequest, "Invalid two-factor code.")
+	}
+
+	codes, err := enableTOTP(user)
+	if err != nil {
+		return err
+	}
+	if err := sessions.LogoutOthers(r, user.Username); err != nil {
+		requestLogger(r).Error("error ending other sessions", "user", user.Username, "cause", causes(err))
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Two-factor authentication enabled.", "recovery_codes": codes})
+}
+
+func TwoFactorDisableHandler(w http.ResponseWriter, r *http.Request) error {
+	user, err := loggedInUser(r)
+	if err != nil {
+		return err
+	}
+
+	var req TwoFactorRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+	if err := req.validate(); err != nil {
+		return NewClientError(err, http.StatusBadRequest, fmt.Sprintf("Invalid form data: %s", err.Error()))
+	}
+	if err := confirmUser(r, user, req.Password); err != nil {
+		return err
+	}
+	if err := checkSecondFactor(user, &req); err != nil {
+		return err
+	}
+
+	if err := disableTOTP(user.Username); err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"message": "Two-factor authentication disabled."})
+}
+
+// RecoveryCodesHandler reports how many recovery codes are left, or replaces
+// them all with new ones.
+func RecoveryCodesHandler(w http.ResponseWriter, r *http.Request) error {
+	user, err := loggedInUser(r)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return NewClientError(nil, http.StatusBadRequest, "Two-factor authentication is not enabled")
+	}
+
+	if r.Method == http.MethodGet {
+		remaining, err := countRecoveryCodes(user.Username)
+		if err != nil {
+			return err
+		}
+		return writeJSON(w, http.StatusOK, map[string]interface{}{"remaining": remaining})
+	}
+
+	var req TwoFactorRequest
+	if err := decodeJSON(w, r, &req); err != nil {
+		return err
+	}
+	if len(req.Code) == 0 {
+		return NewClientError(ErrorMissingField("code"), http.StatusBadRequest, "Invalid form data: code is required.")
+	}
+	req.RecoveryCode = ""
+	if err := checkSecondFactor(user, &req); err != nil {
+		return err
+	}
+
+	codes, err := regenerateRecoveryCodes(user.Username)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	return writeJSON(w, http.StatusOK, map[string]interface{}{"recovery_codes": codes})
+}
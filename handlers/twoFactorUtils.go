@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"PamQ/config"
+	db "PamQ/database"
+	"PamQ/sessions"
+	"PamQ/tokens"
+	"PamQ/totp"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	twoFactorPurpose    = "login-2fa"
+	twoFactorCookieName = "login_2fa"
+	twoFactorTTL        = 5 * time.Minute
+	recoveryCodeCount   = 10
+)
+
+// totpIssuer names the account in authenticator apps.
+var totpIssuer = config.String("TOTP_ISSUER", "PamQ")
+
+// TwoFactorRequest proves the second factor with either a code from the
+// authenticator app or an unused recovery code. Password is only needed for
+// changes to the two-factor settings.
+type TwoFactorRequest struct {
+	Password     string `json:"password"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (t *TwoFactorRequest) validate() error {
+	if len(t.Code) == 0 && len(t.RecoveryCode) == 0 {
+		return ErrorMissingField("code")
+	}
+	return nil
+}
+
+// The pending login is bound to the password and TOTP secret, so it stops
+// working if either changes before the second step.
+func twoFactorBinding(user *User) string {
+	return user.HashedPassword + "\x00" + user.TOTPSecret
+}
+
+func setTwoFactorCookie(w http.ResponseWriter, value string) {
+	cookie := &http.Cookie{
+		Name:     twoFactorCookieName,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   sessions.SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if len(value) == 0 {
+		cookie.MaxAge = -1
+	} else {
+		cookie.MaxAge = int(twoFactorTTL.Seconds())
+	}
+	http.SetCookie(w, cookie)
+}
+
+// startTwoFactor remembers that user has passed the first step of the login.
+// No session is created until the second step succeeds.
+func startTwoFactor(w http.ResponseWriter, user *User) {
+	setTwoFactorCookie(w, tokens.Default.Sign(twoFactorPurpose, user.Username, twoFactorBinding(user), twoFactorTTL))
+}
+
+// pendingTwoFactorUser returns the user that passed the first login step.
+func pendingTwoFactorUser(r *http.Request) (*User, error) {
+	cookie, err := r.Cookie(twoFactorCookieName)
+	if err != nil {
+		return nil, NewClientError(err, http.StatusUnauthorized, "Please login with your password first")
+	}
+	user, err := verifyUserToken(cookie.Value, twoFactorPurpose, twoFactorBinding)
+	if err != nil {
+		return nil, NewClientError(err, http.StatusUnauthorized, "Login expired, please login with your password again")
+	}
+	return user, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.Replace(code, "-", "", -1)
+	return strings.Replace(code, " ", "", -1)
+}
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateRecoveryCodes returns codes like "k4zq7-mw2xa", 50 random bits each.
+func generateRecoveryCodes() ([]string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(b))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	return codes, nil
+}
+
+// replaceRecoveryCodes invalidates the recovery codes of username and
+// returns a new set.
+func replaceRecoveryCodes(db execer, username string) ([]string, error) {
+	codes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, NewServerError(err, 500, "Error generating recovery codes")
+	}
+	if _, err := db.Exec(`DELETE FROM user_recovery_code WHERE username=$1`, username); err != nil {
+		return nil, NewServerError(err, 500, "Error saving data to database")
+	}
+	for _, code := range codes {
+		if _, err := db.Exec(`INSERT INTO user_recovery_code (username, code_hash) VALUES ($1, $2)`, username, hashRecoveryCode(code)); err != nil {
+			return nil, NewServerError(err, 500, "Error saving data to database")
+		}
+	}
+	return codes, nil
+}
+
+func regenerateRecoveryCodes(username string) ([]string, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, NewServerError(err, 500, "Error starting database transaction")
+	}
+	defer tx.Rollback()
+
+	codes, err := replaceRecoveryCodes(tx, username)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, NewServerError(err, 500, "Error saving data to database")
+	}
+	return codes, nil
+}
+
+func countRecoveryCodes(username string) (int, error) {
+	var n int
+	db := db.DB
+	if err := db.QueryRow(`SELECT COUNT(*) FROM user_recovery_code WHERE username=$1 AND used_at IS NULL`, username).Scan(&n); err != nil {
+		return 0, NewServerError(err, 500, "Error fetching data from database")
+	}
+	return n, nil
+}
+
+// useTOTPCode accepts a code only once: its step must be later than the last
+// one used by the user.
+func useTOTPCode(user *User, code string) (bool, error) {
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok || step <= user.TOTPLastStep {
+		return false, nil
+	}
+	db := db.DB
+	res, err := db.Exec(`UPDATE userinfo SET totp_last_step=$2 WHERE username=$1 AND totp_last_step < $2`, user.Username, step)
+	if err != nil {
+		return false, NewServerError(err, 500, "Error saving data to database")
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	user.TOTPLastStep = step
+	return true, nil
+}
+
+func useRecoveryCode(username, code string) (bool, error) {
+	db := db.DB
+	var hash string
+	err := db.QueryRow(`UPDATE user_recovery_code SET used_at=NOW() WHERE username=$1 AND code_hash=$2 AND used_at IS NULL RETURNING code_hash`,
+		username, hashRecoveryCode(code)).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return false, nil
+	} else if err != nil {
+		return false, NewServerError(err, 500, "Error saving data to database")
+	}
+	return true, nil
+}
+
+// checkSecondFactor verifies the TOTP or recovery code of req for user.
+func checkSecondFactor(user *User, req *TwoFactorRequest) error {
+	if !user.TOTPEnabled {
+		return NewClientError(nil, http.StatusBadRequest, "Two-factor authentication is not enabled")
+	}
+	var ok bool
+	var err error
+	if len(req.RecoveryCode) != 0 {
+		ok, err = useRecoveryCode(user.Username, req.RecoveryCode)
+	} else {
+		ok, err = useTOTPCode(user, req.Code)
+	}
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return NewClientError(nil, http.StatusUnauthorized, "Invalid two-factor code.")
+	}
+	return nil
+}
+
+// setTOTPSecret starts an enrolment. The secret isn't used for logins until
+// it is confirmed with enableTOTP.
+func setTOTPSecret(username, secret string) error {
+	db := db.DB
+	res, err := db.Exec(`UPDATE userinfo SET totp_secret=$2, totp_last_step=0 WHERE username=$1 AND NOT totp_enabled`, username, secret)
+	if err != nil {
+		return NewServerError(err, 500, "Error saving data to database")
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return NewClientError(nil, http.StatusConflict, "Two-factor authentication is already enabled")
+	}
+	return nil
+}
+
+// enableTOTP turns on two-factor authentication for user and returns their
+// recovery codes.
+func enableTOTP(user *User) ([]string, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, NewServerError(err, 500, "Error starting database transaction")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE userinfo SET totp_enabled=TRUE WHERE username=$1`, user.Username); err != nil {
+		return nil, NewServerError(err, 500, "Error saving data to database")
+	}
+	codes, err := replaceRecoveryCodes(tx, user.Username)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, NewServerError(err, 500, "Error saving data to database")
+	}
+	user.TOTPEnabled = true
+	return codes, nil
+}
+
+func disableTOTP(username string) error {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return NewServerError(err, 500, "Error starting database transaction")
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE userinfo SET totp_enabled=FALSE, totp_secret=NULL, totp_last_step=0 WHERE username=$1`, username); err != nil {
+		return NewServerError(err, 500, "Error saving data to database")
+	}
+	if _, err := tx.Exec(`DELETE FROM user_recovery_code WHERE username=$1`, username); err != nil {
+		return NewServerError(err, 500, "Error saving data to database")
+	}
+	if err := tx.Commit(); err != nil {
+		return NewServerError(err, 500, "Error saving data to database")
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package handlers_test
+
+import (
+	"PamQ/handlers"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCSRFProtect(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	h := handlers.CSRFProtect(ok)
+
+	tt := []struct {
+		name       string
+		method     string
+		cookie     string
+		header     string
+		origin     string
+		statusCode int
+	}{
+		{name: "Safe method", method: http.MethodGet, statusCode: http.StatusOK},
+		{name: "Missing cookie", method: http.MethodPost, header: "abc", statusCode: http.StatusForbidden},
+		{name: "Missing header", method: http.MethodPost, cookie: "abc", statusCode: http.StatusForbidden},
+		{name: "Mismatched token", method: http.MethodPost, cookie: "abc", header: "abd", statusCode: http.StatusForbidden},
+		{name: "Matching token", method: http.MethodDelete, cookie: "abc", header: "abc", statusCode: http.StatusOK},
+		{name: "Same origin", method: http.MethodPost, cookie: "abc", header: "abc", origin: "http://example.com", statusCode: http.StatusOK},
+		{name: "Cross origin", method: http.MethodPost, cookie: "abc", header: "abc", origin: "http://evil.example", statusCode: http.StatusForbidden},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "http://example.com/api/logout", nil)
+			if len(tc.cookie) != 0 {
+				req.AddCookie(&http.Cookie{Name: handlers.CSRFCookieName, Value: tc.cookie})
+			}
+			if len(tc.header) != 0 {
+				req.Header.Set(handlers.CSRFHeaderName, tc.header)
+			}
+			if len(tc.origin) != 0 {
+				req.Header.Set("Origin", tc.origin)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, rec.Code)
+			}
+		})
+	}
+}
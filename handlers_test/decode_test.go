@@ -0,0 +1,131 @@
+package handlers_test
+
+import (
+	"PamQ/handlers"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewQuizDecoding(t *testing.T) {
+	tt := []struct {
+		name  string
+		input string
+		field string
+		code  string
+	}{
+		{
+			name:  "Typed questions",
+			input: `{"name": "q", "questions": [{"type": 1, "statement": "a", "option1": "x", "option2": "y", "answer": "2"}, {"type": "2", "statement": "b"}]}`,
+		},
+		{
+			name:  "Option of a short answer question",
+			input: `{"name": "q", "questions": [{"type": 1, "statement": "a", "option1": "x", "option2": "y"}, {"type": 2, "statement": "b", "option1": "x"}]}`,
+			field: "questions[1].option1",
+			code:  "unknown",
+		},
+		{
+			name:  "Missing type",
+			input: `{"name": "q", "questions": [{"statement": "a"}]}`,
+			field: "questions[0].type",
+			code:  "required",
+		},
+		{
+			name:  "Invalid type",
+			input: `{"name": "q", "questions": [{"type": 3, "statement": "a"}]}`,
+			field: "questions[0].type",
+			code:  "invalid",
+		},
+		{
+			name:  "Wrongly typed field",
+			input: `{"name": "q", "questions": [{"type": 2, "statement": 5}]}`,
+			field: "questions[0].statement",
+			code:  "invalid_type",
+		},
+		{
+			name:  "Unknown quiz field",
+			input: `{"name": "q", "colour": "red", "questions": []}`,
+			field: "colour",
+			code:  "unknown",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var quiz handlers.NewQuiz
+			err := json.Unmarshal([]byte(tc.input), &quiz)
+			if len(tc.field) == 0 {
+				if err != nil {
+					t.Fatalf("Want no error, got %v", err)
+				}
+				if len(quiz.Questions) != 2 || quiz.Questions[1].Type != handlers.ShortAnswer {
+					t.Errorf("Want a multichoice and a short answer question, got %+v", quiz.Questions)
+				}
+				return
+			}
+
+			var fieldErr handlers.FieldError
+			var missing handlers.ErrorMissingField
+			switch {
+			case errors.As(err, &fieldErr):
+			case errors.As(err, &missing):
+				fieldErr = handlers.FieldError{Field: string(missing), Code: "required"}
+			default:
+				t.Fatalf("Want a field error, got %v", err)
+			}
+			if fieldErr.Field != tc.field || fieldErr.Code != tc.code {
+				t.Errorf("Want %s error on '%s', got %s error on '%s'", tc.code, tc.field, fieldErr.Code, fieldErr.Field)
+			}
+		})
+	}
+}
+
+func TestStrictRequestBodies(t *testing.T) {
+	tt := []struct {
+		name       string
+		input      string
+		statusCode int
+		code       string
+	}{
+		{
+			name:       "Unknown field",
+			input:      `{"username": "sara", "nickname": "s"}`,
+			statusCode: http.StatusBadRequest,
+			code:       handlers.CodeValidation,
+		},
+		{
+			name:       "Trailing data",
+			input:      `{"username": "sara"} {}`,
+			statusCode: http.StatusBadRequest,
+			code:       handlers.CodeInvalidJSON,
+		},
+		{
+			name:       "Too large",
+			input:      `{"username": "` + strings.Repeat("a", 2<<20) + `"}`,
+			statusCode: http.StatusRequestEntityTooLarge,
+			code:       handlers.CodePayloadTooLarge,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/api/signup", strings.NewReader(tc.input))
+			responseRecorder := httptest.NewRecorder()
+			handlers.RootHandler(handlers.SignupHandler).ServeHTTP(responseRecorder, request)
+
+			if responseRecorder.Code != tc.statusCode {
+				t.Fatalf("Want status '%d', got '%d'", tc.statusCode, responseRecorder.Code)
+			}
+			var problem handlers.Problem
+			if err := json.Unmarshal(responseRecorder.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+			if problem.Code != tc.code {
+				t.Errorf("Want code '%s', got '%s'", tc.code, problem.Code)
+			}
+		})
+	}
+}
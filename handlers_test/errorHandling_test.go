@@ -0,0 +1,120 @@
+package handlers_test
+
+import (
+	"PamQ/handlers"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestErrorResponses(t *testing.T) {
+	tt := []struct {
+		name       string
+		err        error
+		statusCode int
+		code       string
+		field      string
+	}{
+		{
+			name:       "Missing field",
+			err:        handlers.NewClientError(handlers.ErrorMissingField("name"), http.StatusBadRequest, "Invalid form data: name is required."),
+			statusCode: http.StatusBadRequest,
+			code:       handlers.CodeValidation,
+			field:      "name",
+		},
+		{
+			name:       "Invalid field",
+			err:        handlers.NewClientError(handlers.FieldError{Field: "role", Code: "invalid", Message: "Bad role"}, http.StatusBadRequest, "Invalid form data: Bad role"),
+			statusCode: http.StatusBadRequest,
+			code:       handlers.CodeValidation,
+			field:      "role",
+		},
+		{
+			name:       "Not found",
+			err:        handlers.NewClientError(nil, http.StatusNotFound, "Quiz not found"),
+			statusCode: http.StatusNotFound,
+			code:       handlers.CodeNotFound,
+		},
+		{
+			name:       "Unique violation",
+			err:        handlers.NewServerError(&pq.Error{Code: "23505", Detail: "Key (email)=(a@b.c) already exists."}, 500, "Error saving data to database"),
+			statusCode: http.StatusConflict,
+			code:       handlers.CodeConflict,
+		},
+		{
+			name:       "Foreign key violation",
+			err:        handlers.NewServerError(fmt.Errorf("insert: %w", &pq.Error{Code: "23503"}), 500, "Error saving data to database"),
+			statusCode: http.StatusUnprocessableEntity,
+			code:       handlers.CodeUnprocessable,
+		},
+		{
+			name:       "Plain error",
+			err:        errors.New("secret connection string"),
+			statusCode: http.StatusInternalServerError,
+			code:       handlers.CodeInternal,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/test", nil)
+			rec := httptest.NewRecorder()
+			handlers.RootHandler(func(http.ResponseWriter, *http.Request) error { return tc.err }).ServeHTTP(rec, req)
+
+			if rec.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, rec.Code)
+			}
+			if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/problem+json") {
+				t.Errorf("Want problem+json, got '%s'", ct)
+			}
+			if body := rec.Body.String(); strings.Contains(body, "secret") || strings.Contains(body, "a@b.c") {
+				t.Errorf("Response leaks the cause: %s", body)
+			}
+
+			var problem handlers.Problem
+			if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Invalid response body: %v", err)
+			}
+			if problem.Code != tc.code {
+				t.Errorf("Want code '%s', got '%s'", tc.code, problem.Code)
+			}
+			if problem.Status != tc.statusCode || problem.Instance != "/api/test" {
+				t.Errorf("Unexpected problem %+v", problem)
+			}
+			if len(tc.field) != 0 && (len(problem.Errors) != 1 || problem.Errors[0].Field != tc.field) {
+				t.Errorf("Want error for field '%s', got %+v", tc.field, problem.Errors)
+			}
+		})
+	}
+}
+
+func TestValidationReportsEveryField(t *testing.T) {
+	input := `{"username": "a green crocodile", "password":"1234", "password_confirm": "12345", "email":"fo.com"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/signup", strings.NewReader(input))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handlers.RootHandler(handlers.SignupHandler).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Want status '%d', got '%d'", http.StatusBadRequest, rec.Code)
+	}
+	var problem handlers.Problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("Invalid response body: %v", err)
+	}
+	want := []string{"username", "email", "password", "password_confirm"}
+	if len(problem.Errors) != len(want) {
+		t.Fatalf("Want errors for %v, got %+v", want, problem.Errors)
+	}
+	for i, field := range want {
+		if problem.Errors[i].Field != field {
+			t.Errorf("Want error %d for field '%s', got '%s'", i, field, problem.Errors[i].Field)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package handlers_test
+
+import (
+	"PamQ/handlers"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthChecks(t *testing.T) {
+	tt := []struct {
+		name       string
+		handler    func(http.ResponseWriter, *http.Request) error
+		draining   bool
+		statusCode int
+		status     string
+	}{
+		{name: "Alive", handler: handlers.HealthzHandler, statusCode: http.StatusOK, status: "ok"},
+		{name: "Draining", handler: handlers.ReadyzHandler, draining: true, statusCode: http.StatusServiceUnavailable, status: "shutting down"},
+		{name: "Alive while draining", handler: handlers.HealthzHandler, draining: true, statusCode: http.StatusOK, status: "ok"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.draining {
+				handlers.StartDraining()
+			}
+			rec := httptest.NewRecorder()
+			handlers.RootHandler(tc.handler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if rec.Code != tc.statusCode {
+				t.Errorf("Want status '%d', got '%d'", tc.statusCode, rec.Code)
+			}
+			var body struct {
+				Status string `json:"status"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Invalid response body: %v", err)
+			}
+			if body.Status != tc.status {
+				t.Errorf("Want status '%s', got '%s'", tc.status, body.Status)
+			}
+		})
+	}
+}
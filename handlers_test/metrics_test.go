@@ -0,0 +1,39 @@
+package handlers_test
+
+import (
+	"PamQ/handlers"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestMetricsCountUnmatchedRequests(t *testing.T) {
+	r := mux.NewRouter()
+	r.Use(handlers.RecordRoute)
+	r.HandleFunc("/metrics-test/{id}", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodGet)
+	handler := handlers.Metrics(r)
+
+	for _, req := range []struct{ method, target string }{
+		{http.MethodGet, "/metrics-test/1"},
+		{http.MethodGet, "/metrics-test/2"},
+		{http.MethodGet, "/metrics-test-missing"},
+		{http.MethodPost, "/metrics-test/1"},
+	} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(req.method, req.target, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	handlers.MetricsHandler(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`pamq_http_requests_total{method="GET",route="/metrics-test/{id}",status="200"} 2`,
+		`pamq_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`pamq_http_requests_total{method="POST",route="unmatched",status="405"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), want+"\n") {
+			t.Errorf("Want line '%s' in:\n%s", want, rec.Body)
+		}
+	}
+}
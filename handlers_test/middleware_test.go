@@ -0,0 +1,70 @@
+package handlers_test
+
+import (
+	"PamQ/apitokens"
+	"PamQ/handlers"
+	"PamQ/sessions"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTokenScopes(t *testing.T) {
+	whoami := func(w http.ResponseWriter, r *http.Request) error {
+		username, ok := sessions.GetUsername(r)
+		if !ok {
+			username = "anonymous"
+		}
+		w.Write([]byte(username))
+		return nil
+	}
+
+	tt := []struct {
+		name       string
+		handler    handlers.RootHandler
+		scopes     []string
+		statusCode int
+		user       string
+	}{
+		{"Granted", handlers.WithScope(apitokens.QuizRead, whoami), []string{apitokens.QuizRead}, http.StatusOK, "sara"},
+		{"Missing scope", handlers.WithScope(apitokens.QuizRead, whoami), []string{apitokens.QuizSubmit}, http.StatusForbidden, ""},
+		{"Public granted", handlers.WithPublicScope(apitokens.QuizRead, whoami), []string{apitokens.QuizRead}, http.StatusOK, "sara"},
+		{"Public missing scope", handlers.WithPublicScope(apitokens.QuizRead, whoami), []string{apitokens.QuizSubmit}, http.StatusOK, "anonymous"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := sessions.WithToken(httptest.NewRequest(http.MethodGet, "/", nil), "sara", tc.scopes)
+			rec := httptest.NewRecorder()
+			tc.handler.ServeHTTP(rec, request)
+			if rec.Code != tc.statusCode {
+				t.Fatalf("Want status '%d', got '%d'", tc.statusCode, rec.Code)
+			}
+			if len(tc.user) != 0 && rec.Body.String() != tc.user {
+				t.Errorf("Want user '%s', got '%s'", tc.user, rec.Body)
+			}
+		})
+	}
+}
+
+// Token submissions are limited per user, however many addresses they come
+// from.
+func TestSubmissionLimitedPerTokenUser(t *testing.T) {
+	handler := handlers.LimitSubmission(handlers.WithScope(apitokens.QuizSubmit, func(w http.ResponseWriter, r *http.Request) error {
+		return nil
+	}))
+
+	limited := false
+	for i := 0; i < 20 && !limited; i++ {
+		request := httptest.NewRequest(http.MethodPost, "/", nil)
+		request.RemoteAddr = fmt.Sprintf("192.0.2.%d:1234", i)
+		request = sessions.WithToken(request, "token_submitter", []string{apitokens.QuizSubmit})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, request)
+		limited = rec.Code == http.StatusTooManyRequests
+	}
+	if !limited {
+		t.Error("Want submissions of one user from many addresses to be limited")
+	}
+}
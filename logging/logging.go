@@ -0,0 +1,213 @@
+// Package logging writes structured log records as JSON objects or as
+// key=value text, one per line. Records carry a message and key/value pairs,
+// with the keys given first: logger.Info("quiz created", "quiz_id", 4).
+package logging
+
+import (
+	"PamQ/config"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	Debug Level = iota + 1
+	Info
+	Warn
+	Error
+)
+
+func (l Level) String() string {
+	s := [...]string{"DEBUG", "INFO", "WARN", "ERROR"}
+	if l >= Debug && l <= Error {
+		return s[l-1]
+	}
+	return "UNKNOWN"
+}
+
+// ParseLevel accepts the level names in any case.
+func ParseLevel(s string) (Level, bool) {
+	for l := Debug; l <= Error; l++ {
+		if strings.EqualFold(s, l.String()) {
+			return l, true
+		}
+	}
+	return 0, false
+}
+
+type Format int
+
+const (
+	Text Format = iota + 1
+	JSON
+)
+
+type output struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format Format
+	level  Level
+}
+
+// Logger is safe for concurrent use. Loggers derived with With share the
+// output of their parent.
+type Logger struct {
+	out   *output
+	attrs []interface{}
+}
+
+func New(w io.Writer, format Format, level Level) *Logger {
+	return &Logger{out: &output{w: w, format: format, level: level}}
+}
+
+// Default logs to stderr with the format and level of PAMQ_LOG_FORMAT
+// (text or json) and PAMQ_LOG_LEVEL.
+var Default = newDefault()
+
+func newDefault() *Logger {
+	format := Text
+	if strings.EqualFold(config.String("LOG_FORMAT", "text"), "json") {
+		format = JSON
+	}
+	level, ok := ParseLevel(config.String("LOG_LEVEL", "info"))
+	if !ok {
+		level = Info
+	}
+	return New(os.Stderr, format, level)
+}
+
+// With returns a logger adding kv to every record.
+func (l *Logger) With(kv ...interface{}) *Logger {
+	attrs := make([]interface{}, 0, len(l.attrs)+len(kv))
+	attrs = append(attrs, l.attrs...)
+	attrs = append(attrs, kv...)
+	return &Logger{out: l.out, attrs: attrs}
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.out.level
+}
+
+func (l *Logger) Debug(msg string, kv ...interface{}) { l.Log(Debug, msg, kv...) }
+func (l *Logger) Info(msg string, kv ...interface{})  { l.Log(Info, msg, kv...) }
+func (l *Logger) Warn(msg string, kv ...interface{})  { l.Log(Warn, msg, kv...) }
+func (l *Logger) Error(msg string, kv ...interface{}) { l.Log(Error, msg, kv...) }
+
+func (l *Logger) Log(level Level, msg string, kv ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	attrs := append(append([]interface{}{}, l.attrs...), kv...)
+
+	var line []byte
+	if l.out.format == JSON {
+		line = formatJSON(time.Now(), level, msg, attrs)
+	} else {
+		line = formatText(time.Now(), level, msg, attrs)
+	}
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.w.Write(line)
+}
+
+// pairs calls fn for each key/value of attrs. A key without a value is
+// reported under "!BADKEY", like a value whose key isn't a string.
+func pairs(attrs []interface{}, fn func(key string, value interface{})) {
+	for i := 0; i < len(attrs); i += 2 {
+		key, ok := attrs[i].(string)
+		if !ok || i+1 == len(attrs) {
+			fn("!BADKEY", attrs[i])
+			i--
+			continue
+		}
+		fn(key, attrs[i+1])
+	}
+}
+
+func value(v interface{}) interface{} {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return v
+}
+
+func formatJSON(t time.Time, level Level, msg string, attrs []interface{}) []byte {
+	var b strings.Builder
+	b.WriteString(`{"time":`)
+	b.WriteString(strconv.Quote(t.Format(time.RFC3339Nano)))
+	b.WriteString(`,"level":"`)
+	b.WriteString(level.String())
+	b.WriteString(`","msg":`)
+	writeJSONValue(&b, msg)
+	pairs(attrs, func(key string, v interface{}) {
+		b.WriteByte(',')
+		writeJSONValue(&b, key)
+		b.WriteByte(':')
+		writeJSONValue(&b, value(v))
+	})
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
+
+func writeJSONValue(b *strings.Builder, v interface{}) {
+	js, err := json.Marshal(v)
+	if err != nil {
+		js, _ = json.Marshal(fmt.Sprint(v))
+	}
+	b.Write(js)
+}
+
+func formatText(t time.Time, level Level, msg string, attrs []interface{}) []byte {
+	var b strings.Builder
+	b.WriteString("time=")
+	b.WriteString(t.Format(time.RFC3339Nano))
+	b.WriteString(" level=")
+	b.WriteString(level.String())
+	b.WriteString(" msg=")
+	writeTextValue(&b, msg)
+	pairs(attrs, func(key string, v interface{}) {
+		b.WriteByte(' ')
+		b.WriteString(key)
+		b.WriteByte('=')
+		writeTextValue(&b, fmt.Sprint(value(v)))
+	})
+	b.WriteByte('\n')
+	return []byte(b.String())
+}
+
+func writeTextValue(b *strings.Builder, s string) {
+	if len(s) == 0 || strings.ContainsAny(s, " =\"\n\t") {
+		b.WriteString(strconv.Quote(s))
+		return
+	}
+	b.WriteString(s)
+}
+
+type loggerKey struct{}
+
+// NewContext returns ctx carrying l.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger of ctx, or Default.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*Logger); ok {
+		return l
+	}
+	return Default
+}
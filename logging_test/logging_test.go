@@ -0,0 +1,56 @@
+package logging_test
+
+import (
+	"PamQ/logging"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, logging.JSON, logging.Info).With("request_id", "abc")
+	logger.Debug("hidden")
+	logger.Info("request", "status", 200, "error", errors.New("boom"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Want 1 record, got %d: %q", len(lines), buf.String())
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Invalid JSON record: %v", err)
+	}
+	want := map[string]interface{}{"level": "INFO", "msg": "request", "request_id": "abc", "status": 200.0, "error": "boom"}
+	for k, v := range want {
+		if record[k] != v {
+			t.Errorf("Want %s '%v', got '%v'", k, v, record[k])
+		}
+	}
+}
+
+func TestText(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logging.New(&buf, logging.Text, logging.Debug)
+	logger.Warn("slow request", "path", "/api/quiz/all", "note", "took a while", "odd")
+
+	line := buf.String()
+	for _, part := range []string{`level=WARN`, `msg="slow request"`, `path=/api/quiz/all`, `note="took a while"`, `!BADKEY=odd`} {
+		if !strings.Contains(line, part) {
+			t.Errorf("Want '%s' in '%s'", part, line)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if logging.FromContext(context.Background()) != logging.Default {
+		t.Errorf("Want the default logger without one in the context")
+	}
+	logger := logging.New(&bytes.Buffer{}, logging.Text, logging.Info)
+	if logging.FromContext(logging.NewContext(context.Background(), logger)) != logger {
+		t.Errorf("Want the logger of the context")
+	}
+}
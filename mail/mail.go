@@ -0,0 +1,109 @@
+// Package mail sends email to users. The SMTP mailer is meant for production
+// and the log mailer for local development.
+package mail
+
+import (
+	"PamQ/config"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Default is chosen with PAMQ_MAILER, "smtp" or "log".
+var Default = NewFromConfig()
+
+func NewFromConfig() Mailer {
+	from := config.String("MAIL_FROM", "PamQ <no-reply@localhost>")
+	if config.String("MAILER", "log") == "smtp" {
+		return &SMTPMailer{
+			Addr:     config.String("SMTP_ADDR", "localhost:587"),
+			Username: config.String("SMTP_USERNAME", ""),
+			Password: config.String("SMTP_PASSWORD", ""),
+			From:     from,
+		}
+	}
+	return &LogMailer{Path: config.String("MAIL_LOG_PATH", ""), From: from}
+}
+
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.Replace(msg.Body, "\n", "\r\n", -1))
+	return []byte(b.String())
+}
+
+type SMTPMailer struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	if strings.ContainsAny(msg.To+msg.Subject, "\r\n") {
+		return fmt.Errorf("mail: invalid header value")
+	}
+
+	var auth smtp.Auth
+	if len(m.Username) != 0 {
+		host, _, err := net.SplitHostPort(m.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	from := m.From
+	if i := strings.LastIndex(from, "<"); i >= 0 {
+		from = strings.TrimSuffix(from[i+1:], ">")
+	}
+	return smtp.SendMail(m.Addr, auth, from, []string{msg.To}, format(m.From, msg))
+}
+
+// LogMailer appends messages to the file at Path, or to the standard logger
+// when Path is empty.
+type LogMailer struct {
+	Path string
+	From string
+
+	mu sync.Mutex
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	if len(m.Path) == 0 {
+		log.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f, err := os.OpenFile(m.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(format(m.From, msg), "\r\n\r\n"...)); err != nil {
+		return err
+	}
+	return nil
+}
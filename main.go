@@ -1,11 +1,19 @@
 package main
 
 import (
+	"PamQ/config"
+	db "PamQ/database"
 	"PamQ/handlers"
+	"PamQ/logging"
+	"PamQ/router"
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
-	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
 )
 
@@ -14,21 +22,44 @@ func main() {
 }
 
 func startServer() {
-	// db.InitDB()
-	r := mux.NewRouter()
+	// The request logger and the metrics wrap the router so unmatched requests
+	// are logged and counted too.
+	srv := &http.Server{
+		Addr:              config.String("ADDR", ":8080"),
+		Handler:           handlers.RequestLogger(handlers.Metrics(router.New())),
+		ReadHeaderTimeout: config.Duration("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		ReadTimeout:       config.Duration("HTTP_READ_TIMEOUT", 30*time.Second),
+		// Exports of large quizzes are streamed, so writes get more time.
+		WriteTimeout: config.Duration("HTTP_WRITE_TIMEOUT", 5*time.Minute),
+		IdleTimeout:  config.Duration("HTTP_IDLE_TIMEOUT", 2*time.Minute),
+	}
 
-	api := r.PathPrefix("/api").Subrouter()
-	api.Handle("/signup", handlers.RootHandler(handlers.SignupHandler)).Methods(http.MethodPost)
-	api.Handle("/login", handlers.RootHandler(handlers.LoginHandler)).Methods(http.MethodPost)
-	api.Handle("/logout", handlers.RootHandler(handlers.LogoutHandler)).Methods(http.MethodPost)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+		sig := <-stop
 
-	quiz := api.PathPrefix("/quiz").Subrouter()
-	quiz.Handle("/create", handlers.RootHandler(handlers.CreateQuizHandler)).Methods(http.MethodPost)
-	quiz.Handle("/all", handlers.RootHandler(handlers.ListOfQuizesHandler)).Methods(http.MethodGet)
-	quiz.Handle("/results", handlers.RootHandler(handlers.QuizResultsHandler)).Methods(http.MethodGet, http.MethodGet)
-	quiz.Handle("/{quizID}", handlers.RootHandler(handlers.QuizHandler)).Methods(http.MethodGet, http.MethodPost)
+		// Fail readiness checks first and keep serving until load balancers
+		// have noticed, then stop taking new connections and let in-flight
+		// requests, such as quiz submissions, finish.
+		logging.Default.Info("shutting down", "signal", sig.String())
+		handlers.StartDraining()
+		time.Sleep(config.Duration("SHUTDOWN_DRAIN_DELAY", 5*time.Second))
+		ctx, cancel := context.WithTimeout(context.Background(), config.Duration("SHUTDOWN_TIMEOUT", 30*time.Second))
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			logging.Default.Error("graceful shutdown failed", "error", err)
+		}
+	}()
 
-	if err := http.ListenAndServe(":8080", r); err != nil {
+	logging.Default.Info("listening", "addr", srv.Addr)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
 		log.Fatal(err)
 	}
+	<-done
+	if err := db.DB.Close(); err != nil {
+		logging.Default.Error("error closing database", "error", err)
+	}
 }
@@ -0,0 +1,49 @@
+package metrics_test
+
+import (
+	"PamQ/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExposition(t *testing.T) {
+	r := &metrics.Registry{}
+	requests := r.NewCounter("requests_total", "Requests.", "route", "status")
+	latency := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	r.NewGaugeFunc("connections", "Connections.", func() float64 { return 3 })
+
+	requests.Inc("/api/quiz/{quizID}", "200")
+	requests.Add(2, "/api/quiz/{quizID}", "200")
+	requests.Inc(`/a"b`, "404")
+	latency.Observe(0.05, "/x")
+	latency.Observe(0.1, "/x")
+	latency.Observe(5, "/x")
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+
+	want := []string{
+		"# TYPE requests_total counter",
+		`requests_total{route="/api/quiz/{quizID}",status="200"} 3`,
+		`requests_total{route="/a\"b",status="404"} 1`,
+		"# TYPE latency_seconds histogram",
+		`latency_seconds_bucket{route="/x",le="0.1"} 2`,
+		`latency_seconds_bucket{route="/x",le="1"} 2`,
+		`latency_seconds_bucket{route="/x",le="+Inf"} 3`,
+		`latency_seconds_sum{route="/x"} 5.15`,
+		`latency_seconds_count{route="/x"} 3`,
+		"# TYPE connections gauge",
+		"connections 3",
+	}
+	for _, line := range want {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Want line '%s' in:\n%s", line, body)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type '%s'", ct)
+	}
+}
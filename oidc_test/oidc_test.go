@@ -0,0 +1,216 @@
+package oidc_test
+
+import (
+	"PamQ/oidc"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// mockProvider is a minimal OpenID provider issuing RS256 ID tokens.
+type mockProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// codes maps issued authorization codes to their PKCE challenge and
+	// nonce.
+	codes  map[string][2]string
+	claims map[string]interface{}
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockProvider{key: key, codes: map[string][2]string{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.server.URL,
+			"authorization_endpoint": m.server.URL + "/authorize",
+			"token_endpoint":         m.server.URL + "/token",
+			"jwks_uri":               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-key",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		issued, ok := m.codes[r.PostForm.Get("code")]
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != issued[0] {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		claims := map[string]interface{}{
+			"iss":            m.server.URL,
+			"sub":            "user-1",
+			"aud":            "pamq",
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          issued[1],
+			"email":          "user@example.com",
+			"email_verified": true,
+		}
+		for k, v := range m.claims {
+			claims[k] = v
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": m.sign(t, claims)})
+	})
+	m.server = httptest.NewServer(mux)
+	return m
+}
+
+func (m *mockProvider) sign(t *testing.T, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test-key", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// authorize plays the user signing in at the provider and returns the code
+// sent back to the redirect URL.
+func (m *mockProvider) authorize(t *testing.T, authURL string) string {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("Want code_challenge_method 'S256', got '%s'", q.Get("code_challenge_method"))
+	}
+	code := "code-" + q.Get("state")
+	m.codes[code] = [2]string{q.Get("code_challenge"), q.Get("nonce")}
+	return code
+}
+
+func TestExchange(t *testing.T) {
+	tt := []struct {
+		name        string
+		claims      map[string]interface{}
+		verifier    string
+		nonce       string
+		wantSubject string
+	}{
+		{
+			name:        "Valid login",
+			wantSubject: "user-1",
+		},
+		{
+			name:        "Audience list",
+			claims:      map[string]interface{}{"aud": []string{"other", "pamq"}},
+			wantSubject: "user-1",
+		},
+		{
+			name:     "Wrong PKCE verifier",
+			verifier: "not-the-verifier",
+		},
+		{
+			name:  "Wrong nonce",
+			nonce: "not-the-nonce",
+		},
+		{
+			name:   "Other audience",
+			claims: map[string]interface{}{"aud": "other"},
+		},
+		{
+			name:   "Expired token",
+			claims: map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()},
+		},
+		{
+			name:   "Other issuer",
+			claims: map[string]interface{}{"iss": "https://evil.example.com"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := newMockProvider(t)
+			defer mock.server.Close()
+			mock.claims = tc.claims
+
+			provider := &oidc.Provider{
+				Name:        "mock",
+				Issuer:      mock.server.URL,
+				ClientID:    "pamq",
+				RedirectURL: "http://localhost:8080/api/oidc/mock/callback",
+				Scopes:      []string{"openid", "email"},
+			}
+
+			state, _ := oidc.RandomString()
+			nonce, _ := oidc.RandomString()
+			verifier, _ := oidc.RandomString()
+			authURL, err := provider.AuthCodeURL(context.Background(), state, nonce, verifier)
+			if err != nil {
+				t.Fatalf("AuthCodeURL: %v", err)
+			}
+			code := mock.authorize(t, authURL)
+
+			if len(tc.verifier) != 0 {
+				verifier = tc.verifier
+			}
+			if len(tc.nonce) != 0 {
+				nonce = tc.nonce
+			}
+			claims, err := provider.Exchange(context.Background(), code, verifier, nonce)
+			if len(tc.wantSubject) == 0 {
+				if err == nil {
+					t.Errorf("Want error, got claims for '%s'", claims.Subject)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if claims.Subject != tc.wantSubject || claims.Email != "user@example.com" || !claims.EmailVerified {
+				t.Errorf("Unexpected claims %+v", claims)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsForgedSignature(t *testing.T) {
+	mock := newMockProvider(t)
+	defer mock.server.Close()
+	provider := &oidc.Provider{Issuer: mock.server.URL, ClientID: "pamq"}
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mock.key = other
+	raw := mock.sign(t, map[string]interface{}{
+		"iss":   mock.server.URL,
+		"sub":   "user-1",
+		"aud":   "pamq",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": "n",
+	})
+	if _, err := provider.Verify(context.Background(), raw, "n"); err != oidc.ErrInvalidToken {
+		t.Errorf("Want '%v', got '%v'", oidc.ErrInvalidToken, err)
+	}
+}
@@ -0,0 +1,288 @@
+// Package openapi holds the OpenAPI 3 document of the API and checks JSON
+// documents against its schemas. It understands the subset of JSON Schema
+// the document uses: $ref, type, nullable, enum, properties, required,
+// additionalProperties, items and allOf.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Document is a parsed OpenAPI document.
+type Document struct {
+	raw  []byte
+	root map[string]interface{}
+}
+
+// Spec is the document describing this API.
+var Spec = MustParse([]byte(spec))
+
+// Parse reads an OpenAPI document.
+func Parse(b []byte) (*Document, error) {
+	var root map[string]interface{}
+	if err := json.Unmarshal(b, &root); err != nil {
+		return nil, err
+	}
+	return &Document{raw: b, root: root}, nil
+}
+
+func MustParse(b []byte) *Document {
+	d, err := Parse(b)
+	if err != nil {
+		panic(fmt.Sprintf("openapi: %v", err))
+	}
+	return d
+}
+
+// Handler serves Spec.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(Spec.raw)
+}
+
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Operations lists the operations of d as "METHOD /path", sorted.
+func (d *Document) Operations() []string {
+	var list []string
+	paths, _ := d.root["paths"].(map[string]interface{})
+	for path, item := range paths {
+		item, _ := item.(map[string]interface{})
+		for _, method := range methods {
+			if _, ok := item[method]; ok {
+				list = append(list, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Operation returns the operation for method on the path template path.
+func (d *Document) Operation(method, path string) (map[string]interface{}, bool) {
+	paths, _ := d.root["paths"].(map[string]interface{})
+	item, _ := paths[path].(map[string]interface{})
+	op, ok := item[strings.ToLower(method)].(map[string]interface{})
+	return op, ok
+}
+
+// resolve follows the $ref of v, if any, within d.
+func (d *Document) resolve(v map[string]interface{}) (map[string]interface{}, error) {
+	for i := 0; i < 10; i++ {
+		ref, ok := v["$ref"].(string)
+		if !ok {
+			return v, nil
+		}
+		if !strings.HasPrefix(ref, "#/") {
+			return nil, fmt.Errorf("unsupported $ref %q", ref)
+		}
+		var node interface{} = d.root
+		for _, part := range strings.Split(ref[2:], "/") {
+			m, _ := node.(map[string]interface{})
+			node = m[part]
+		}
+		if v, ok = node.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("$ref %q not found", ref)
+		}
+	}
+	return nil, fmt.Errorf("$ref cycle")
+}
+
+// ValidateResponse checks a response of the operation against the schema
+// documented for its status and content type.
+func (d *Document) ValidateResponse(method, path string, status int, contentType string, body []byte) error {
+	op, ok := d.Operation(method, path)
+	if !ok {
+		return fmt.Errorf("%s %s is not documented", method, path)
+	}
+	responses, _ := op["responses"].(map[string]interface{})
+	response, ok := responses[strconv.Itoa(status)].(map[string]interface{})
+	if !ok {
+		if response, ok = responses["default"].(map[string]interface{}); !ok {
+			return fmt.Errorf("%s %s: status %d is not documented", method, path, status)
+		}
+	}
+	response, err := d.resolve(response)
+	if err != nil {
+		return err
+	}
+
+	content, _ := response["content"].(map[string]interface{})
+	if len(content) == 0 {
+		if len(body) != 0 {
+			return fmt.Errorf("%s %s: status %d has no documented body", method, path, status)
+		}
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	media, ok := content[mediaType].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s %s: content type %q is not documented for status %d", method, path, contentType, status)
+	}
+	schema, ok := media["schema"].(map[string]interface{})
+	if !ok || (mediaType != "application/json" && mediaType != "application/problem+json") {
+		return nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Errorf("%s %s: %v", method, path, err)
+	}
+	return d.validate(schema, v, "body")
+}
+
+// ValidateSchema checks the JSON document b against the component schema
+// name.
+func (d *Document) ValidateSchema(name string, b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	return d.validate(map[string]interface{}{"$ref": "#/components/schemas/" + name}, v, name)
+}
+
+func (d *Document) validate(schema map[string]interface{}, v interface{}, at string) error {
+	schema, err := d.resolve(schema)
+	if err != nil {
+		return fmt.Errorf("%s: %v", at, err)
+	}
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		if schema, err = d.flatten(schema, all); err != nil {
+			return fmt.Errorf("%s: %v", at, err)
+		}
+	}
+
+	if v == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable || schema["type"] == nil {
+			return nil
+		}
+		return fmt.Errorf("%s: is null", at)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !contains(enum, v) {
+		return fmt.Errorf("%s: %v is not one of %v", at, v, enum)
+	}
+
+	switch t, _ := schema["type"].(string); t {
+	case "":
+		return nil
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%s: want a string, got %T", at, v)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: want a boolean, got %T", at, v)
+		}
+	case "number", "integer":
+		n, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("%s: want a %s, got %T", at, t, v)
+		}
+		if t == "integer" && n != math.Trunc(n) {
+			return fmt.Errorf("%s: want an integer, got %v", at, n)
+		}
+	case "array":
+		list, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: want an array, got %T", at, v)
+		}
+		items, _ := schema["items"].(map[string]interface{})
+		for i, item := range list {
+			if err := d.validate(items, item, fmt.Sprintf("%s[%d]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		return d.validateObject(schema, v, at)
+	default:
+		return fmt.Errorf("%s: unsupported type %q", at, t)
+	}
+	return nil
+}
+
+func (d *Document) validateObject(schema map[string]interface{}, v interface{}, at string) error {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s: want an object, got %T", at, v)
+	}
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				return fmt.Errorf("%s: %s is missing", at, name)
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		prop, ok := properties[k].(map[string]interface{})
+		if !ok {
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					return fmt.Errorf("%s: %s is not documented", at, k)
+				}
+				continue
+			case map[string]interface{}:
+				prop = extra
+			default:
+				continue
+			}
+		}
+		if err := d.validate(prop, obj[k], at+"."+k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flatten merges the members of allOf into one object schema, so that
+// additionalProperties considers the properties of all of them.
+func (d *Document) flatten(schema map[string]interface{}, all []interface{}) (map[string]interface{}, error) {
+	merged := map[string]interface{}{"type": "object"}
+	properties := map[string]interface{}{}
+	var required []interface{}
+	parts := append([]interface{}{schema}, all...)
+	for _, part := range parts {
+		part, _ := part.(map[string]interface{})
+		part, err := d.resolve(part)
+		if err != nil {
+			return nil, err
+		}
+		if props, ok := part["properties"].(map[string]interface{}); ok {
+			for k, v := range props {
+				properties[k] = v
+			}
+		}
+		if req, ok := part["required"].([]interface{}); ok {
+			required = append(required, req...)
+		}
+		if extra, ok := part["additionalProperties"]; ok {
+			merged["additionalProperties"] = extra
+		}
+	}
+	merged["properties"] = properties
+	merged["required"] = required
+	return merged, nil
+}
+
+func contains(list []interface{}, v interface{}) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,3436 @@
+package openapi
+
+// spec is the OpenAPI document served at /api/v1/openapi.json. Every route of
+// the router needs an operation here; router_test checks both agree.
+const spec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "PamQ",
+    "version": "1.0.0",
+    "description": "Create quizzes, take them and look at their results.\n\nThe API is versioned: every route lives under /api/v1. The same routes under /api are deprecated aliases; their responses carry Deprecation, Sunset and Link (rel=successor-version) headers."
+  },
+  "servers": [
+    {
+      "url": "/"
+    }
+  ],
+  "security": [
+    {
+      "cookieAuth": []
+    }
+  ],
+  "paths": {
+    "/metrics": {
+      "get": {
+        "summary": "Metrics in the Prometheus text format",
+        "description": "Requires the PAMQ_METRICS_TOKEN bearer token when it is set. Without it the metrics are public, so deployments that don't set it must keep this path off the public network.",
+        "tags": [
+          "operations"
+        ],
+        "security": [
+          {
+            "metricsToken": []
+          },
+          {}
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "text/plain": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/healthz": {
+      "get": {
+        "summary": "Liveness check",
+        "tags": [
+          "operations"
+        ],
+        "security": [],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Health"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/readyz": {
+      "get": {
+        "summary": "Readiness check",
+        "tags": [
+          "operations"
+        ],
+        "security": [],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Health"
+                }
+              }
+            }
+          },
+          "503": {
+            "description": "Not ready",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Health"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/openapi.json": {
+      "get": {
+        "summary": "This document",
+        "tags": [
+          "meta"
+        ],
+        "security": [],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/csrf": {
+      "get": {
+        "summary": "Get a CSRF token",
+        "description": "Sets the csrf_token cookie if needed. Its value must be sent in the X-CSRF-Token header of state-changing requests authenticated with a session cookie.",
+        "tags": [
+          "auth"
+        ],
+        "security": [],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/CSRFToken"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/signup": {
+      "post": {
+        "summary": "Create an account",
+        "tags": [
+          "auth"
+        ],
+        "security": [],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/NewUser"
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Message"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/login": {
+      "post": {
+        "summary": "Log in",
+        "description": "Starts a session. When two-factor authentication is enabled, two_factor_required is set and the login is finished with /api/v1/login/2fa. After PAMQ_LOGIN_MAX_FAILURES (5) wrong passwords or codes in a row for a username from one IP address, logins of it from that address fail with 429 for PAMQ_LOGIN_LOCKOUT (15 minutes); other addresses are only rate limited.",
+        "tags": [
+          "auth"
+        ],
+        "security": [],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/LoginCredentials"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/LoginResult"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/login/2fa": {
+      "post": {
+        "summary": "Finish a login with a second factor",
+        "tags": [
+          "auth"
+        ],
+        "security": [],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/TwoFactorRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/LoginResult"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/logout": {
+      "post": {
+        "summary": "Log out",
+        "tags": [
+          "auth"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Message"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/oidc/{provider}/login": {
+      "get": {
+        "summary": "Log in with an identity provider",
+        "tags": [
+          "auth"
+        ],
+        "security": [],
+        "parameters": [
+          {
+            "name": "provider",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "302": {
+            "description": "Redirect"
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/oidc/{provider}/callback": {
+      "get": {
+        "summary": "Identity provider callback",
+        "description": "A first login with an email already used by an account is linked to it only when the provider allows it and both verified the email; otherwise it fails with 409 and has to be linked while logged in.",
+        "tags": [
+          "auth"
+        ],
+        "security": [],
+        "parameters": [
+          {
+            "name": "provider",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "302": {
+            "description": "Redirect"
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/logout/all": {
+      "post": {
+        "summary": "Log out of every session",
+        "description": "API tokens are kept; revoke them with DELETE /api/tokens/{tokenID}.",
+        "tags": [
+          "auth"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Message"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/sessions": {
+      "get": {
+        "summary": "List sessions",
+        "tags": [
+          "auth"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "required": [
+                    "sessions"
+                  ],
+                  "properties": {
+                    "sessions": {
+                      "type": "array",
+                      "items": {
+                        "$ref": "#/components/schemas/Session"
+                      }
+                    }
+                  },
+                  "additionalProperties": false
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/sessions/{sessionID}": {
+      "delete": {
+        "summary": "Revoke a session",
+        "tags": [
+          "auth"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "parameters": [
+          {
+            "name": "sessionID",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Message"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/me": {
+      "get": {
+        "summary": "Get the account",
+        "tags": [
+          "account"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/User"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      },
+      "patch": {
+        "summary": "Update the account",
+        "description": "Emails are compared without case and stored in lower case. An email used by another account fails with 409. Changing the password logs out the other sessions but keeps API tokens.",
+        "tags": [
+          "account"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ProfileUpdate"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/User"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      },
+      "delete": {
+        "summary": "Delete the account",
+        "tags": [
+          "account"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/AccountDeletion"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Message"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/2fa/setup": {
+      "post": {
+        "summary": "Start setting up two-factor authentication",
+        "tags": [
+          "account"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/TwoFactorRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/TwoFactorSetup"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/2fa/enable": {
+      "post": {
+        "summary": "Enable two-factor authentication",
+        "tags": [
+          "account"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/TwoFactorRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/RecoveryCodes"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/2fa/disable": {
+      "post": {
+        "summary": "Disable two-factor authentication",
+        "tags": [
+          "account"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/TwoFactorRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Message"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/2fa/recovery-codes": {
+      "get": {
+        "summary": "Count unused recovery codes",
+        "tags": [
+          "account"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "required": [
+                    "remaining"
+                  ],
+                  "properties": {
+                    "remaining": {
+                      "type": "integer"
+                    }
+                  },
+                  "additionalProperties": false
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      },
+      "post": {
+        "summary": "Replace the recovery codes",
+        "tags": [
+          "account"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/TwoFactorRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/RecoveryCodes"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/verify-email": {
+      "post": {
+        "summary": "Verify an email address",
+        "tags": [
+          "account"
+        ],
+        "security": [],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/TokenRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "required": [
+                    "message",
+                    "email"
+                  ],
+                  "properties": {
+                    "message": {
+                      "type": "string"
+                    },
+                    "email": {
+                      "type": "string"
+                    }
+                  },
+                  "additionalProperties": false
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/verify-email/resend": {
+      "post": {
+        "summary": "Send the verification email again",
+        "tags": [
+          "account"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Message"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/password/forgot": {
+      "post": {
+        "summary": "Send a password reset email",
+        "tags": [
+          "account"
+        ],
+        "security": [],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/EmailRequest"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Message"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/password/reset": {
+      "post": {
+        "summary": "Reset a password",
+        "description": "Ends every session of the account and revokes its API tokens.",
+        "tags": [
+          "account"
+        ],
+        "security": [],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/PasswordReset"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Message"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/tokens": {
+      "get": {
+        "summary": "List API tokens",
+        "tags": [
+          "tokens"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "required": [
+                    "tokens"
+                  ],
+                  "properties": {
+                    "tokens": {
+                      "type": "array",
+                      "items": {
+                        "$ref": "#/components/schemas/APIToken"
+                      }
+                    }
+                  },
+                  "additionalProperties": false
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      },
+      "post": {
+        "summary": "Create an API token",
+        "tags": [
+          "tokens"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/NewToken"
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/CreatedToken"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/tokens/{tokenID}": {
+      "delete": {
+        "summary": "Revoke an API token",
+        "tags": [
+          "tokens"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "parameters": [
+          {
+            "name": "tokenID",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "integer"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Message"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/categories": {
+      "get": {
+        "summary": "Get the category tree",
+        "tags": [
+          "categories"
+        ],
+        "security": [],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "required": [
+                    "categories"
+                  ],
+                  "properties": {
+                    "categories": {
+                      "type": "array",
+                      "items": {
+                        "$ref": "#/components/schemas/Category"
+                      }
+                    }
+                  },
+                  "additionalProperties": false
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      },
+      "post": {
+        "summary": "Create a category",
+        "description": "Admins only.",
+        "tags": [
+          "categories"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/CategoryUpdate"
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Created"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/categories/{categoryID}": {
+      "patch": {
+        "summary": "Rename or move a category",
+        "description": "Admins only. A parent_id of 0 moves the category to the top.",
+        "tags": [
+          "categories"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "parameters": [
+          {
+            "name": "categoryID",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "integer"
+            }
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/CategoryUpdate"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Message"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      },
+      "delete": {
+        "summary": "Delete a category",
+        "description": "Admins only. Categories having subcategories can't be deleted.",
+        "tags": [
+          "categories"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          }
+        ],
+        "parameters": [
+          {
+            "name": "categoryID",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "integer"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Message"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/quiz/create": {
+      "post": {
+        "summary": "Create a quiz",
+        "tags": [
+          "quizzes"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          },
+          {
+            "bearerAuth": [
+              "quiz:write"
+            ]
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/NewQuiz"
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Created"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/quiz/all": {
+      "get": {
+        "summary": "List quizzes",
+        "tags": [
+          "quizzes"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          },
+          {
+            "bearerAuth": [
+              "quiz:read"
+            ]
+          },
+          {}
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/IfNoneMatch"
+          },
+          {
+            "name": "creator",
+            "in": "query",
+            "description": "Only quizzes created by this user. createdby is accepted too.",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "grading_type",
+            "in": "query",
+            "description": "Only quizzes with this grading type.",
+            "schema": {
+              "type": "integer",
+              "enum": [
+                1,
+                2
+              ]
+            }
+          },
+          {
+            "name": "tag",
+            "in": "query",
+            "description": "Only quizzes having every given tag.",
+            "schema": {
+              "type": "array",
+              "items": {
+                "type": "string"
+              }
+            }
+          },
+          {
+            "name": "category",
+            "in": "query",
+            "description": "Only quizzes in this category or its subcategories.",
+            "schema": {
+              "type": "integer"
+            }
+          },
+          {
+            "name": "open",
+            "in": "query",
+            "description": "Only quizzes whose announced opening times include, or exclude, the current time.",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "q",
+            "in": "query",
+            "description": "Full text search in names and descriptions.",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "sort",
+            "in": "query",
+            "description": "Defaults to newest.",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "newest",
+                "most_taken",
+                "name"
+              ]
+            }
+          },
+          {
+            "name": "cursor",
+            "in": "query",
+            "description": "The next_cursor of the previous page.",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "Page size.",
+            "schema": {
+              "type": "integer"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/QuizList"
+                }
+              }
+            }
+          },
+          "304": {
+            "description": "The client's copy, named in If-None-Match or If-Modified-Since, is current."
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/quiz/results": {
+      "get": {
+        "summary": "List the participations of the user",
+        "tags": [
+          "quizzes"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          },
+          {
+            "bearerAuth": [
+              "results:read"
+            ]
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "required": [
+                    "participations"
+                  ],
+                  "properties": {
+                    "participations": {
+                      "type": "array",
+                      "items": {
+                        "$ref": "#/components/schemas/QuizParticipation"
+                      },
+                      "nullable": true
+                    }
+                  },
+                  "additionalProperties": false
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/quiz/{quizID}": {
+      "get": {
+        "summary": "Get a quiz to take",
+        "description": "Answers and result texts are left out. The ETag starts with the quiz version; views of logged in users are private as they include their remaining participations.",
+        "tags": [
+          "quizzes"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          },
+          {
+            "bearerAuth": [
+              "quiz:read"
+            ]
+          },
+          {}
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/QuizID"
+          },
+          {
+            "$ref": "#/components/parameters/IfNoneMatch"
+          },
+          {
+            "name": "If-Modified-Since",
+            "in": "header",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/QuizView"
+                }
+              }
+            }
+          },
+          "304": {
+            "description": "The client's copy, named in If-None-Match or If-Modified-Since, is current."
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      },
+      "post": {
+        "summary": "Submit answers",
+        "description": "Answers outside the opening times of the quiz fail with 403, and answers beyond the participation limit with 400; both are checked while the participation is recorded. Keys are kept for 24 hours by default. Reusing it for other answers fails with 422, and retrying while the first submission is still processed fails with 409.",
+        "tags": [
+          "quizzes"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          },
+          {
+            "bearerAuth": [
+              "quiz:submit"
+            ]
+          }
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/QuizID"
+          },
+          {
+            "name": "Idempotency-Key",
+            "in": "header",
+            "description": "Up to 255 printable ASCII characters chosen by the client. Retries with the same key and answers get the stored result, marked by an Idempotent-Replayed header, instead of being scored again.",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "description": "Answers keyed by question id.",
+                "type": "object",
+                "properties": {},
+                "additionalProperties": {
+                  "type": "string"
+                }
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/SubmitResult"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      },
+      "put": {
+        "summary": "Edit a quiz",
+        "description": "Questions having an id are updated in place; the others are added and missing ones are deleted.",
+        "tags": [
+          "quizzes"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          },
+          {
+            "bearerAuth": [
+              "quiz:write"
+            ]
+          }
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/QuizID"
+          },
+          {
+            "name": "If-Match",
+            "in": "header",
+            "description": "ETags of the quiz the edit is based on. The edit fails with 412 if the quiz has changed since.",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/NewQuiz"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/QuizUpdated"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/quiz/{quizID}/collaborators": {
+      "get": {
+        "summary": "List collaborators",
+        "tags": [
+          "collaborators"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          },
+          {
+            "bearerAuth": [
+              "quiz:write"
+            ]
+          }
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/QuizID"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "required": [
+                    "collaborators"
+                  ],
+                  "properties": {
+                    "collaborators": {
+                      "type": "array",
+                      "items": {
+                        "$ref": "#/components/schemas/Collaborator"
+                      }
+                    }
+                  },
+                  "additionalProperties": false
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      },
+      "post": {
+        "summary": "Add or change a collaborator",
+        "tags": [
+          "collaborators"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          },
+          {
+            "bearerAuth": [
+              "quiz:write"
+            ]
+          }
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/QuizID"
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/NewCollaborator"
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "required": [
+                    "message",
+                    "username",
+                    "role"
+                  ],
+                  "properties": {
+                    "message": {
+                      "type": "string"
+                    },
+                    "username": {
+                      "type": "string"
+                    },
+                    "role": {
+                      "$ref": "#/components/schemas/Role"
+                    }
+                  },
+                  "additionalProperties": false
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/quiz/{quizID}/collaborators/{username}": {
+      "delete": {
+        "summary": "Remove a collaborator",
+        "tags": [
+          "collaborators"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          },
+          {
+            "bearerAuth": [
+              "quiz:write"
+            ]
+          }
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/QuizID"
+          },
+          {
+            "name": "username",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Message"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/quiz/{quizID}/audit": {
+      "get": {
+        "summary": "Get the audit log of a quiz",
+        "tags": [
+          "collaborators"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          },
+          {
+            "bearerAuth": [
+              "quiz:read"
+            ]
+          }
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/QuizID"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "required": [
+                    "audit"
+                  ],
+                  "properties": {
+                    "audit": {
+                      "type": "array",
+                      "items": {
+                        "$ref": "#/components/schemas/AuditEntry"
+                      }
+                    }
+                  },
+                  "additionalProperties": false
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/quiz/{quizID}/participations": {
+      "get": {
+        "summary": "List participations",
+        "tags": [
+          "results"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          },
+          {
+            "bearerAuth": [
+              "results:read"
+            ]
+          }
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/QuizID"
+          },
+          {
+            "name": "from",
+            "in": "query",
+            "description": "Only participations after this time.",
+            "schema": {
+              "type": "string",
+              "format": "date-time"
+            }
+          },
+          {
+            "name": "to",
+            "in": "query",
+            "description": "Only participations before this time.",
+            "schema": {
+              "type": "string",
+              "format": "date-time"
+            }
+          },
+          {
+            "name": "pass",
+            "in": "query",
+            "description": "Only passed, or failed, participations.",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "user",
+            "in": "query",
+            "description": "Only participations of this user.",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "sort",
+            "in": "query",
+            "description": "Defaults to date.",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "date",
+                "score",
+                "username"
+              ]
+            }
+          },
+          {
+            "name": "order",
+            "in": "query",
+            "description": "Sort order.",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "asc",
+                "desc"
+              ]
+            }
+          },
+          {
+            "name": "page",
+            "in": "query",
+            "description": "Page number, from 1.",
+            "schema": {
+              "type": "integer"
+            }
+          },
+          {
+            "name": "per_page",
+            "in": "query",
+            "description": "Page size.",
+            "schema": {
+              "type": "integer"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ParticipationPage"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/quiz/{quizID}/leaderboard": {
+      "get": {
+        "summary": "Get the leaderboard",
+        "tags": [
+          "results"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          },
+          {
+            "bearerAuth": [
+              "quiz:read"
+            ]
+          },
+          {}
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/QuizID"
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "Number of entries.",
+            "schema": {
+              "type": "integer"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Leaderboard"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/quiz/{quizID}/analysis": {
+      "get": {
+        "summary": "Get the item analysis",
+        "tags": [
+          "results"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          },
+          {
+            "bearerAuth": [
+              "results:read"
+            ]
+          }
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/QuizID"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/QuizAnalysis"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/quiz/{quizID}/participations/export": {
+      "get": {
+        "summary": "Export participations",
+        "description": "Accepts the filters of the participations list.",
+        "tags": [
+          "results"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          },
+          {
+            "bearerAuth": [
+              "results:read"
+            ]
+          }
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/QuizID"
+          },
+          {
+            "name": "format",
+            "in": "query",
+            "required": true,
+            "description": "File format.",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "csv",
+                "xlsx"
+              ]
+            }
+          },
+          {
+            "name": "questions",
+            "in": "query",
+            "description": "Add a column per question.",
+            "schema": {
+              "type": "boolean"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "text/csv": {
+                "schema": {
+                  "type": "string",
+                  "format": "binary"
+                }
+              },
+              "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": {
+                "schema": {
+                  "type": "string",
+                  "format": "binary"
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    },
+    "/api/v1/quiz/{quizID}/participations/{participationID}/grade": {
+      "post": {
+        "summary": "Grade a participation",
+        "tags": [
+          "results"
+        ],
+        "security": [
+          {
+            "cookieAuth": []
+          },
+          {
+            "bearerAuth": [
+              "results:grade"
+            ]
+          }
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/QuizID"
+          },
+          {
+            "name": "participationID",
+            "in": "path",
+            "required": true,
+            "schema": {
+              "type": "integer"
+            }
+          }
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/ManualGrade"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "OK",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "required": [
+                    "message",
+                    "result",
+                    "score",
+                    "pass"
+                  ],
+                  "properties": {
+                    "message": {
+                      "type": "string"
+                    },
+                    "result": {
+                      "type": "string"
+                    },
+                    "score": {
+                      "type": "number"
+                    },
+                    "pass": {
+                      "type": "boolean"
+                    }
+                  },
+                  "additionalProperties": false
+                }
+              }
+            }
+          },
+          "default": {
+            "$ref": "#/components/responses/Problem"
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "cookieAuth": {
+        "type": "apiKey",
+        "in": "cookie",
+        "name": "session"
+      },
+      "bearerAuth": {
+        "type": "http",
+        "scheme": "bearer",
+        "description": "An API token. Tokens only reach the routes of their scopes."
+      },
+      "metricsToken": {
+        "type": "http",
+        "scheme": "bearer"
+      }
+    },
+    "parameters": {
+      "QuizID": {
+        "name": "quizID",
+        "in": "path",
+        "required": true,
+        "schema": {
+          "type": "integer"
+        }
+      },
+      "IfNoneMatch": {
+        "name": "If-None-Match",
+        "in": "header",
+        "description": "The ETag of the copy the client has.",
+        "schema": {
+          "type": "string"
+        }
+      }
+    },
+    "responses": {
+      "Problem": {
+        "description": "Error",
+        "content": {
+          "application/problem+json": {
+            "schema": {
+              "$ref": "#/components/schemas/Problem"
+            }
+          }
+        }
+      }
+    },
+    "schemas": {
+      "Message": {
+        "type": "object",
+        "required": [
+          "message"
+        ],
+        "properties": {
+          "message": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "Created": {
+        "type": "object",
+        "required": [
+          "message",
+          "id"
+        ],
+        "properties": {
+          "message": {
+            "type": "string"
+          },
+          "id": {
+            "type": "integer"
+          }
+        },
+        "additionalProperties": false
+      },
+      "QuizUpdated": {
+        "type": "object",
+        "required": [
+          "message",
+          "id",
+          "version"
+        ],
+        "properties": {
+          "message": {
+            "type": "string"
+          },
+          "id": {
+            "type": "integer"
+          },
+          "version": {
+            "type": "integer"
+          }
+        },
+        "additionalProperties": false
+      },
+      "FieldError": {
+        "type": "object",
+        "required": [
+          "field",
+          "code",
+          "message"
+        ],
+        "properties": {
+          "field": {
+            "type": "string"
+          },
+          "code": {
+            "type": "string"
+          },
+          "message": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "Problem": {
+        "description": "An RFC 7807 problem. code is stable and meant for programs; errors lists invalid fields.",
+        "type": "object",
+        "required": [
+          "type",
+          "title",
+          "status",
+          "code",
+          "detail"
+        ],
+        "properties": {
+          "type": {
+            "type": "string"
+          },
+          "title": {
+            "type": "string"
+          },
+          "status": {
+            "type": "integer"
+          },
+          "code": {
+            "type": "string"
+          },
+          "detail": {
+            "type": "string"
+          },
+          "instance": {
+            "type": "string"
+          },
+          "errors": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/FieldError"
+            }
+          }
+        },
+        "additionalProperties": false
+      },
+      "Health": {
+        "type": "object",
+        "required": [
+          "status"
+        ],
+        "properties": {
+          "status": {
+            "type": "string"
+          },
+          "checks": {
+            "type": "object",
+            "additionalProperties": {
+              "type": "string"
+            }
+          }
+        },
+        "additionalProperties": false
+      },
+      "CSRFToken": {
+        "type": "object",
+        "required": [
+          "csrf_token",
+          "header"
+        ],
+        "properties": {
+          "csrf_token": {
+            "type": "string"
+          },
+          "header": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "NewUser": {
+        "type": "object",
+        "required": [
+          "username",
+          "email",
+          "password",
+          "password_confirm"
+        ],
+        "properties": {
+          "username": {
+            "type": "string"
+          },
+          "email": {
+            "type": "string",
+            "format": "email"
+          },
+          "password": {
+            "type": "string"
+          },
+          "password_confirm": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "LoginCredentials": {
+        "description": "Either username or email identifies the user.",
+        "type": "object",
+        "required": [
+          "password"
+        ],
+        "properties": {
+          "username": {
+            "type": "string"
+          },
+          "email": {
+            "type": "string"
+          },
+          "password": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "LoginResult": {
+        "type": "object",
+        "required": [
+          "message"
+        ],
+        "properties": {
+          "message": {
+            "type": "string"
+          },
+          "username": {
+            "type": "string"
+          },
+          "two_factor_required": {
+            "type": "boolean"
+          }
+        },
+        "additionalProperties": false
+      },
+      "TwoFactorRequest": {
+        "description": "Accounts without a password leave it out and must have signed in within 10 minutes.",
+        "type": "object",
+        "properties": {
+          "password": {
+            "type": "string"
+          },
+          "code": {
+            "type": "string"
+          },
+          "recovery_code": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "TwoFactorSetup": {
+        "type": "object",
+        "required": [
+          "secret",
+          "provisioning_uri"
+        ],
+        "properties": {
+          "secret": {
+            "type": "string"
+          },
+          "provisioning_uri": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "RecoveryCodes": {
+        "type": "object",
+        "required": [
+          "recovery_codes"
+        ],
+        "properties": {
+          "message": {
+            "type": "string"
+          },
+          "recovery_codes": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          }
+        },
+        "additionalProperties": false
+      },
+      "User": {
+        "type": "object",
+        "required": [
+          "username",
+          "email",
+          "email_verified",
+          "display_name",
+          "is_admin",
+          "date_created",
+          "two_factor_enabled"
+        ],
+        "properties": {
+          "username": {
+            "type": "string"
+          },
+          "email": {
+            "type": "string"
+          },
+          "email_verified": {
+            "type": "boolean"
+          },
+          "display_name": {
+            "type": "string"
+          },
+          "is_admin": {
+            "type": "boolean"
+          },
+          "date_created": {
+            "type": "string",
+            "description": "Formatted like \"Monday, 02-Jan-06 15:04\"."
+          },
+          "two_factor_enabled": {
+            "type": "boolean"
+          }
+        },
+        "additionalProperties": false
+      },
+      "ProfileUpdate": {
+        "description": "Only the given fields change. Changing the email or password needs old_password. Accounts created with single sign-on have no password: they may set one, or change their email, within 10 minutes of signing in.",
+        "type": "object",
+        "properties": {
+          "display_name": {
+            "type": "string"
+          },
+          "email": {
+            "type": "string"
+          },
+          "password": {
+            "type": "string"
+          },
+          "password_confirm": {
+            "type": "string"
+          },
+          "old_password": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "AccountDeletion": {
+        "description": "Accounts without a password leave it out and must have signed in within 10 minutes.",
+        "type": "object",
+        "properties": {
+          "password": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "TokenRequest": {
+        "type": "object",
+        "required": [
+          "token"
+        ],
+        "properties": {
+          "token": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "EmailRequest": {
+        "type": "object",
+        "required": [
+          "email"
+        ],
+        "properties": {
+          "email": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "PasswordReset": {
+        "type": "object",
+        "required": [
+          "token",
+          "password",
+          "password_confirm"
+        ],
+        "properties": {
+          "token": {
+            "type": "string"
+          },
+          "password": {
+            "type": "string"
+          },
+          "password_confirm": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "Session": {
+        "type": "object",
+        "required": [
+          "id",
+          "user_agent",
+          "ip",
+          "date_created",
+          "last_seen",
+          "expires_at",
+          "current"
+        ],
+        "properties": {
+          "id": {
+            "type": "string"
+          },
+          "user_agent": {
+            "type": "string"
+          },
+          "ip": {
+            "type": "string"
+          },
+          "date_created": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "last_seen": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "expires_at": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "current": {
+            "type": "boolean"
+          }
+        },
+        "additionalProperties": false
+      },
+      "Scope": {
+        "type": "string",
+        "enum": [
+          "quiz:read",
+          "quiz:write",
+          "quiz:submit",
+          "results:read",
+          "results:grade"
+        ]
+      },
+      "NewToken": {
+        "type": "object",
+        "required": [
+          "name",
+          "scopes"
+        ],
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "scopes": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Scope"
+            }
+          },
+          "expires_in_days": {
+            "type": "integer"
+          }
+        },
+        "additionalProperties": false
+      },
+      "APIToken": {
+        "type": "object",
+        "required": [
+          "id",
+          "name",
+          "prefix",
+          "scopes",
+          "expires_at",
+          "last_used",
+          "date_created"
+        ],
+        "properties": {
+          "id": {
+            "type": "integer"
+          },
+          "name": {
+            "type": "string"
+          },
+          "prefix": {
+            "type": "string"
+          },
+          "scopes": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Scope"
+            }
+          },
+          "expires_at": {
+            "type": "string",
+            "format": "date-time",
+            "nullable": true
+          },
+          "last_used": {
+            "type": "string",
+            "format": "date-time",
+            "nullable": true
+          },
+          "date_created": {
+            "type": "string",
+            "format": "date-time"
+          }
+        },
+        "additionalProperties": false
+      },
+      "CreatedToken": {
+        "type": "object",
+        "required": [
+          "message",
+          "token",
+          "details"
+        ],
+        "properties": {
+          "message": {
+            "type": "string"
+          },
+          "token": {
+            "type": "string",
+            "description": "Only shown once."
+          },
+          "details": {
+            "$ref": "#/components/schemas/APIToken"
+          }
+        },
+        "additionalProperties": false
+      },
+      "Category": {
+        "type": "object",
+        "required": [
+          "id",
+          "parent_id",
+          "name",
+          "children",
+          "date_created"
+        ],
+        "properties": {
+          "id": {
+            "type": "integer"
+          },
+          "parent_id": {
+            "type": "integer",
+            "nullable": true
+          },
+          "name": {
+            "type": "string"
+          },
+          "children": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Category"
+            }
+          },
+          "date_created": {
+            "type": "string",
+            "description": "Formatted like \"Monday, 02-Jan-06 15:04\"."
+          }
+        },
+        "additionalProperties": false
+      },
+      "CategoryUpdate": {
+        "type": "object",
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "parent_id": {
+            "type": "integer"
+          }
+        },
+        "additionalProperties": false
+      },
+      "Grading": {
+        "type": "integer",
+        "enum": [
+          1,
+          2
+        ],
+        "description": "1: only correct answers count, 2: wrong answers get a negative mark."
+      },
+      "QuestionType": {
+        "type": "integer",
+        "enum": [
+          1,
+          2
+        ],
+        "description": "1: multiple choice, 2: short answer."
+      },
+      "Question": {
+        "description": "Options are set for multiple choice questions, whose answer is the number of the right option.",
+        "type": "object",
+        "required": [
+          "id",
+          "type",
+          "statement"
+        ],
+        "properties": {
+          "id": {
+            "type": "integer"
+          },
+          "type": {
+            "$ref": "#/components/schemas/QuestionType"
+          },
+          "statement": {
+            "type": "string"
+          },
+          "option1": {
+            "type": "string"
+          },
+          "option2": {
+            "type": "string"
+          },
+          "option3": {
+            "type": "string"
+          },
+          "option4": {
+            "type": "string"
+          },
+          "answer": {
+            "type": "string"
+          }
+        },
+        "additionalProperties": false
+      },
+      "NewQuestion": {
+        "description": "Options are only accepted for multiple choice questions, which need the first two. Unknown fields are rejected.",
+        "type": "object",
+        "required": [
+          "type",
+          "statement"
+        ],
+        "properties": {
+          "id": {
+            "type": "integer",
+            "description": "Set to edit an existing question in place."
+          },
+          "type": {
+            "description": "A QuestionType, as a number or a numeric string."
+          },
+          "statement": {
+            "type": "string",
+            "maxLength": 500
+          },
+          "option1": {
+            "type": "string",
+            "maxLength": 500
+          },
+          "option2": {
+            "type": "string",
+            "maxLength": 500
+          },
+          "option3": {
+            "type": "string",
+            "maxLength": 500
+          },
+          "option4": {
+            "type": "string",
+            "maxLength": 500
+          },
+          "answer": {
+            "type": "string",
+            "maxLength": 500
+          }
+        },
+        "additionalProperties": false
+      },
+      "NewQuiz": {
+        "type": "object",
+        "required": [
+          "name",
+          "questions",
+          "grading_type"
+        ],
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "description": {
+            "type": "string"
+          },
+          "tags": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "category_id": {
+            "type": "integer",
+            "nullable": true
+          },
+          "questions": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/NewQuestion"
+            }
+          },
+          "grading_type": {
+            "$ref": "#/components/schemas/Grading"
+          },
+          "pass_fail": {
+            "type": "boolean"
+          },
+          "passing_score": {
+            "type": "number"
+          },
+          "not_fail_text": {
+            "type": "string"
+          },
+          "fail_text": {
+            "type": "string"
+          },
+          "allowed_participation": {
+            "type": "integer"
+          },
+          "leaderboard": {
+            "type": "boolean"
+          },
+          "leaderboard_show_names": {
+            "type": "boolean"
+          },
+          "opens_at": {
+            "type": "string",
+            "format": "date-time",
+            "nullable": true,
+            "description": "Answers are accepted from this time on."
+          },
+          "closes_at": {
+            "type": "string",
+            "format": "date-time",
+            "nullable": true,
+            "description": "Answers are refused from this time on."
+          }
+        },
+        "additionalProperties": false
+      },
+      "Quiz": {
+        "type": "object",
+        "required": [
+          "id",
+          "creator",
+          "name",
+          "description",
+          "tags",
+          "category_id",
+          "grading_type",
+          "pass_fail",
+          "passing_score",
+          "not_fail_text",
+          "fail_text",
+          "allowed_participation",
+          "leaderboard",
+          "leaderboard_show_names",
+          "opens_at",
+          "closes_at",
+          "version",
+          "date_updated",
+          "date_created"
+        ],
+        "properties": {
+          "id": {
+            "type": "integer"
+          },
+          "creator": {
+            "type": "string"
+          },
+          "name": {
+            "type": "string"
+          },
+          "description": {
+            "type": "string"
+          },
+          "tags": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            }
+          },
+          "category_id": {
+            "type": "integer",
+            "nullable": true
+          },
+          "questions": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Question"
+            }
+          },
+          "grading_type": {
+            "$ref": "#/components/schemas/Grading"
+          },
+          "pass_fail": {
+            "type": "boolean"
+          },
+          "passing_score": {
+            "type": "number"
+          },
+          "not_fail_text": {
+            "type": "string"
+          },
+          "fail_text": {
+            "type": "string"
+          },
+          "allowed_participation": {
+            "type": "integer"
+          },
+          "leaderboard": {
+            "type": "boolean"
+          },
+          "leaderboard_show_names": {
+            "type": "boolean"
+          },
+          "opens_at": {
+            "type": "string",
+            "format": "date-time",
+            "nullable": true
+          },
+          "closes_at": {
+            "type": "string",
+            "format": "date-time",
+            "nullable": true
+          },
+          "version": {
+            "type": "integer",
+            "description": "Increases with every edit."
+          },
+          "date_updated": {
+            "type": "string",
+            "description": "Formatted like \"Monday, 02-Jan-06 15:04\"."
+          },
+          "date_created": {
+            "type": "string",
+            "description": "Formatted like \"Monday, 02-Jan-06 15:04\"."
+          }
+        },
+        "additionalProperties": false
+      },
+      "QuizView": {
+        "allOf": [
+          {
+            "$ref": "#/components/schemas/Quiz"
+          },
+          {
+            "type": "object",
+            "required": [
+              "available_participation"
+            ],
+            "properties": {
+              "available_participation": {
+                "type": "integer"
+              }
+            },
+            "additionalProperties": false
+          }
+        ]
+      },
+      "QuizListItem": {
+        "allOf": [
+          {
+            "$ref": "#/components/schemas/Quiz"
+          },
+          {
+            "type": "object",
+            "required": [
+              "participations"
+            ],
+            "properties": {
+              "participations": {
+                "type": "integer"
+              }
+            },
+            "additionalProperties": false
+          }
+        ]
+      },
+      "QuizList": {
+        "type": "object",
+        "required": [
+          "quizes",
+          "total",
+          "next_cursor"
+        ],
+        "properties": {
+          "quizes": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/QuizListItem"
+            }
+          },
+          "total": {
+            "type": "integer"
+          },
+          "next_cursor": {
+            "type": "string",
+            "nullable": true
+          }
+        },
+        "additionalProperties": false
+      },
+      "AnswerResult": {
+        "type": "integer",
+        "enum": [
+          0,
+          1,
+          2,
+          3
+        ],
+        "description": "0: wrong, 1: no answer, 2: correct, 3: the question has no answer."
+      },
+      "ParticipationAnswer": {
+        "type": "object",
+        "required": [
+          "question_id",
+          "answer",
+          "result",
+          "mark"
+        ],
+        "properties": {
+          "question_id": {
+            "type": "integer"
+          },
+          "answer": {
+            "type": "string"
+          },
+          "result": {
+            "$ref": "#/components/schemas/AnswerResult"
+          },
+          "mark": {
+            "type": "number"
+          }
+        },
+        "additionalProperties": false
+      },
+      "QuizParticipation": {
+        "type": "object",
+        "required": [
+          "ID",
+          "quiz_id",
+          "username",
+          "result",
+          "score",
+          "pass_fail",
+          "date_created"
+        ],
+        "properties": {
+          "ID": {
+            "type": "integer"
+          },
+          "quiz_id": {
+            "type": "integer"
+          },
+          "username": {
+            "type": "string"
+          },
+          "result": {
+            "type": "string"
+          },
+          "score": {
+            "type": "number"
+          },
+          "pass_fail": {
+            "type": "boolean"
+          },
+          "date_created": {
+            "type": "string",
+            "description": "Formatted like \"Monday, 02-Jan-06 15:04\"."
+          },
+          "answers": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ParticipationAnswer"
+            }
+          }
+        },
+        "additionalProperties": false
+      },
+      "SubmitResult": {
+        "description": "The result and the number of answers of each AnswerResult.",
+        "type": "object",
+        "required": [
+          "message",
+          "result",
+          "score",
+          "pass",
+          "Wrong",
+          "NoAnswer",
+          "Correct",
+          "QuestionAnswerNotProvided"
+        ],
+        "properties": {
+          "message": {
+            "type": "string"
+          },
+          "result": {
+            "type": "string"
+          },
+          "score": {
+            "type": "number"
+          },
+          "pass": {
+            "type": "boolean"
+          },
+          "Wrong": {
+            "type": "integer"
+          },
+          "NoAnswer": {
+            "type": "integer"
+          },
+          "Correct": {
+            "type": "integer"
+          },
+          "QuestionAnswerNotProvided": {
+            "type": "integer"
+          }
+        },
+        "additionalProperties": false
+      },
+      "Role": {
+        "type": "integer",
+        "enum": [
+          1,
+          2,
+          3
+        ],
+        "description": "1: editor, 2: grader, 3: viewer."
+      },
+      "Collaborator": {
+        "type": "object",
+        "required": [
+          "username",
+          "role",
+          "added_by",
+          "date_created"
+        ],
+        "properties": {
+          "username": {
+            "type": "string"
+          },
+          "role": {
+            "$ref": "#/components/schemas/Role"
+          },
+          "added_by": {
+            "type": "string"
+          },
+          "date_created": {
+            "type": "string",
+            "description": "Formatted like \"Monday, 02-Jan-06 15:04\"."
+          }
+        },
+        "additionalProperties": false
+      },
+      "NewCollaborator": {
+        "type": "object",
+        "required": [
+          "username",
+          "role"
+        ],
+        "properties": {
+          "username": {
+            "type": "string"
+          },
+          "role": {
+            "$ref": "#/components/schemas/Role"
+          }
+        },
+        "additionalProperties": false
+      },
+      "AuditEntry": {
+        "type": "object",
+        "required": [
+          "id",
+          "quiz_id",
+          "username",
+          "action",
+          "detail",
+          "date_created"
+        ],
+        "properties": {
+          "id": {
+            "type": "integer"
+          },
+          "quiz_id": {
+            "type": "integer"
+          },
+          "username": {
+            "type": "string"
+          },
+          "action": {
+            "type": "string"
+          },
+          "detail": {
+            "type": "string"
+          },
+          "date_created": {
+            "type": "string",
+            "description": "Formatted like \"Monday, 02-Jan-06 15:04\"."
+          }
+        },
+        "additionalProperties": false
+      },
+      "HistogramBin": {
+        "type": "object",
+        "required": [
+          "from",
+          "to",
+          "count"
+        ],
+        "properties": {
+          "from": {
+            "type": "number"
+          },
+          "to": {
+            "type": "number"
+          },
+          "count": {
+            "type": "integer"
+          }
+        },
+        "additionalProperties": false
+      },
+      "ParticipationStats": {
+        "type": "object",
+        "required": [
+          "count",
+          "mean",
+          "median",
+          "pass_rate",
+          "histogram"
+        ],
+        "properties": {
+          "count": {
+            "type": "integer"
+          },
+          "mean": {
+            "type": "number"
+          },
+          "median": {
+            "type": "number"
+          },
+          "pass_rate": {
+            "type": "number"
+          },
+          "histogram": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/HistogramBin"
+            }
+          }
+        },
+        "additionalProperties": false
+      },
+      "ParticipationPage": {
+        "type": "object",
+        "required": [
+          "participations",
+          "page",
+          "per_page",
+          "total",
+          "stats"
+        ],
+        "properties": {
+          "participations": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/QuizParticipation"
+            }
+          },
+          "page": {
+            "type": "integer"
+          },
+          "per_page": {
+            "type": "integer"
+          },
+          "total": {
+            "type": "integer"
+          },
+          "stats": {
+            "$ref": "#/components/schemas/ParticipationStats"
+          }
+        },
+        "additionalProperties": false
+      },
+      "ManualGrade": {
+        "type": "object",
+        "required": [
+          "score"
+        ],
+        "properties": {
+          "score": {
+            "type": "number",
+            "minimum": 0,
+            "maximum": 100
+          }
+        },
+        "additionalProperties": false
+      },
+      "LeaderboardEntry": {
+        "type": "object",
+        "required": [
+          "rank",
+          "name",
+          "score",
+          "completed_at"
+        ],
+        "properties": {
+          "rank": {
+            "type": "integer"
+          },
+          "name": {
+            "type": "string"
+          },
+          "score": {
+            "type": "number"
+          },
+          "completed_at": {
+            "type": "string",
+            "description": "Formatted like \"Monday, 02-Jan-06 15:04\"."
+          },
+          "you": {
+            "type": "boolean"
+          }
+        },
+        "additionalProperties": false
+      },
+      "Leaderboard": {
+        "type": "object",
+        "required": [
+          "quiz_id",
+          "participants",
+          "entries"
+        ],
+        "properties": {
+          "quiz_id": {
+            "type": "integer"
+          },
+          "participants": {
+            "type": "integer"
+          },
+          "entries": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/LeaderboardEntry"
+            }
+          },
+          "me": {
+            "$ref": "#/components/schemas/LeaderboardEntry"
+          }
+        },
+        "additionalProperties": false
+      },
+      "ItemStats": {
+        "type": "object",
+        "required": [
+          "question_id",
+          "statement",
+          "responses",
+          "difficulty",
+          "discrimination"
+        ],
+        "properties": {
+          "question_id": {
+            "type": "integer"
+          },
+          "statement": {
+            "type": "string"
+          },
+          "responses": {
+            "type": "integer"
+          },
+          "difficulty": {
+            "type": "number",
+            "nullable": true
+          },
+          "discrimination": {
+            "type": "number",
+            "nullable": true
+          },
+          "options": {
+            "type": "object",
+            "additionalProperties": {
+              "type": "integer"
+            }
+          }
+        },
+        "additionalProperties": false
+      },
+      "QuizAnalysis": {
+        "type": "object",
+        "required": [
+          "quiz_id",
+          "participations",
+          "items",
+          "cronbach_alpha",
+          "kr20"
+        ],
+        "properties": {
+          "quiz_id": {
+            "type": "integer"
+          },
+          "participations": {
+            "type": "integer"
+          },
+          "items": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ItemStats"
+            }
+          },
+          "cronbach_alpha": {
+            "type": "number",
+            "nullable": true
+          },
+          "kr20": {
+            "type": "number",
+            "nullable": true
+          }
+        },
+        "additionalProperties": false
+      }
+    }
+  }
+}
+`
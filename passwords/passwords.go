@@ -0,0 +1,209 @@
+// Package passwords hashes and verifies user passwords. Passwords are peppered
+// with a server secret before being hashed with bcrypt or argon2id, and
+// Verify reports when a stored hash should be replaced because it was made
+// with outdated parameters.
+package passwords
+
+import (
+	"PamQ/config"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type Algorithm string
+
+const (
+	Bcrypt   Algorithm = "bcrypt"
+	Argon2id Algorithm = "argon2id"
+)
+
+// Hashes created before this package existed used a fixed prefix instead of
+// a pepper, and a low bcrypt cost.
+const (
+	legacySalt       = "SomeSaltHereMaybeThere"
+	legacyBcryptCost = 8
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var ErrMalformedHash = errors.New("passwords: malformed hash")
+
+type Hasher struct {
+	Algorithm     Algorithm
+	BcryptCost    int
+	Argon2Time    uint32
+	Argon2Memory  uint32 // in KiB
+	Argon2Threads uint8
+	Pepper        []byte
+}
+
+// Default is configured from the PAMQ_PASSWORD_* environment variables. The
+// server refuses to start when they are out of range.
+var Default = newDefaultHasher()
+
+func newDefaultHasher() *Hasher {
+	h, err := NewHasherFromConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return h
+}
+
+func NewHasherFromConfig() (*Hasher, error) {
+	algorithm := Algorithm(config.String("PASSWORD_ALGORITHM", string(Argon2id)))
+	if algorithm != Bcrypt && algorithm != Argon2id {
+		return nil, fmt.Errorf("passwords: PAMQ_PASSWORD_ALGORITHM must be %s or %s, got %q", Bcrypt, Argon2id, algorithm)
+	}
+	cost, err := configInt("PASSWORD_BCRYPT_COST", 12, int64(bcrypt.MinCost), int64(bcrypt.MaxCost))
+	if err != nil {
+		return nil, err
+	}
+	time, err := configInt("PASSWORD_ARGON2_TIME", 3, 1, math.MaxUint32)
+	if err != nil {
+		return nil, err
+	}
+	threads, err := configInt("PASSWORD_ARGON2_THREADS", 2, 1, math.MaxUint8)
+	if err != nil {
+		return nil, err
+	}
+	memory, err := configInt("PASSWORD_ARGON2_MEMORY", 64*1024, int64(argon2MinMemory(uint8(threads))), math.MaxUint32)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Hasher{
+		Algorithm:     algorithm,
+		BcryptCost:    cost,
+		Argon2Time:    uint32(time),
+		Argon2Memory:  uint32(memory),
+		Argon2Threads: uint8(threads),
+		Pepper:        []byte(config.String("PASSWORD_PEPPER", "")),
+	}, nil
+}
+
+func configInt(key string, def int, min, max int64) (int, error) {
+	v := config.Int(key, def)
+	if int64(v) < min || int64(v) > max {
+		return 0, fmt.Errorf("passwords: PAMQ_%s must be between %d and %d, got %d", key, min, max, v)
+	}
+	return v, nil
+}
+
+// argon2MinMemory is the least memory, in KiB, argon2 accepts for threads.
+func argon2MinMemory(threads uint8) int {
+	return 8 * int(threads)
+}
+
+func (h *Hasher) pepper(password string) []byte {
+	if len(h.Pepper) == 0 {
+		return []byte(password)
+	}
+	// bcrypt only looks at the first 72 bytes, so the HMAC is encoded
+	// rather than appended to the password.
+	mac := hmac.New(sha256.New, h.Pepper)
+	mac.Write([]byte(password))
+	return []byte(base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+// Hash returns the encoded hash of password with the configured algorithm.
+func (h *Hasher) Hash(password string) (string, error) {
+	peppered := h.pepper(password)
+
+	if h.Algorithm == Bcrypt {
+		hash, err := bcrypt.GenerateFromPassword(peppered, h.BcryptCost)
+		return string(hash), err
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey(peppered, salt, h.Argon2Time, h.Argon2Memory, h.Argon2Threads, argon2KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.Argon2Memory, h.Argon2Time, h.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify reports whether password matches hash and, if it does, whether the
+// hash should be recomputed with the current settings.
+func (h *Hasher) Verify(password, hash string) (ok bool, needsRehash bool, err error) {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		return h.verifyArgon2id(password, hash)
+	}
+	return h.verifyBcrypt(password, hash)
+}
+
+func (h *Hasher) verifyBcrypt(password, hash string) (bool, bool, error) {
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return false, false, ErrMalformedHash
+	}
+	outdated := h.Algorithm != Bcrypt || cost != h.BcryptCost
+
+	err = bcrypt.CompareHashAndPassword([]byte(hash), h.pepper(password))
+	if err == nil {
+		return true, outdated, nil
+	}
+	if err != bcrypt.ErrMismatchedHashAndPassword {
+		return false, false, err
+	}
+
+	if cost == legacyBcryptCost {
+		err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(legacySalt+password))
+		if err == nil {
+			return true, true, nil
+		}
+	}
+	return false, false, nil
+}
+
+func (h *Hasher) verifyArgon2id(password, hash string) (bool, bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, false, ErrMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return false, false, ErrMalformedHash
+	}
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, false, ErrMalformedHash
+	}
+	// argon2 panics on parameters it can't work with.
+	if time < 1 || threads < 1 || int(memory) < argon2MinMemory(threads) {
+		return false, false, ErrMalformedHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) == 0 {
+		return false, false, ErrMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, false, ErrMalformedHash
+	}
+
+	other := argon2.IDKey(h.pepper(password), salt, time, memory, threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return false, false, nil
+	}
+
+	outdated := h.Algorithm != Argon2id || version != argon2.Version ||
+		memory != h.Argon2Memory || time != h.Argon2Time || threads != h.Argon2Threads || len(key) != argon2KeyLength
+	return true, outdated, nil
+}
@@ -0,0 +1,170 @@
+package passwords_test
+
+import (
+	"PamQ/passwords"
+	"os"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cheap parameters keep the tests fast.
+func argon2Hasher() *passwords.Hasher {
+	return &passwords.Hasher{Algorithm: passwords.Argon2id, BcryptCost: bcrypt.MinCost, Argon2Time: 1, Argon2Memory: 64, Argon2Threads: 1}
+}
+
+func bcryptHasher() *passwords.Hasher {
+	h := argon2Hasher()
+	h.Algorithm = passwords.Bcrypt
+	return h
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, h := range []*passwords.Hasher{argon2Hasher(), bcryptHasher()} {
+		t.Run(string(h.Algorithm), func(t *testing.T) {
+			hash, err := h.Hash("S2525fs_23523")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ok, rehash, err := h.Verify("S2525fs_23523", hash)
+			if err != nil || !ok || rehash {
+				t.Errorf("Want a match without rehash, got %v, %v, %v", ok, rehash, err)
+			}
+			ok, _, err = h.Verify("S2525fs_23524", hash)
+			if err != nil || ok {
+				t.Errorf("Want a mismatch, got %v, %v", ok, err)
+			}
+		})
+	}
+}
+
+func TestPepper(t *testing.T) {
+	for _, h := range []*passwords.Hasher{argon2Hasher(), bcryptHasher()} {
+		t.Run(string(h.Algorithm), func(t *testing.T) {
+			h.Pepper = []byte("pepper")
+			hash, err := h.Hash("S2525fs_23523")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok, _, err := h.Verify("S2525fs_23523", hash); err != nil || !ok {
+				t.Errorf("Want a match, got %v, %v", ok, err)
+			}
+
+			h.Pepper = []byte("other")
+			if ok, _, _ := h.Verify("S2525fs_23523", hash); ok {
+				t.Error("Want a mismatch with another pepper")
+			}
+			h.Pepper = nil
+			if ok, _, _ := h.Verify("S2525fs_23523", hash); ok {
+				t.Error("Want a mismatch without the pepper")
+			}
+		})
+	}
+}
+
+func TestLegacyBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("SomeSaltHereMaybeThere"+"S2525fs_23523"), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := argon2Hasher()
+	h.Pepper = []byte("pepper")
+
+	ok, rehash, err := h.Verify("S2525fs_23523", string(legacy))
+	if err != nil || !ok || !rehash {
+		t.Errorf("Want a match needing rehash, got %v, %v, %v", ok, rehash, err)
+	}
+	if ok, _, _ := h.Verify("S2525fs_23524", string(legacy)); ok {
+		t.Error("Want a mismatch")
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	tt := []struct {
+		name   string
+		change func(h *passwords.Hasher)
+	}{
+		{"Other algorithm", func(h *passwords.Hasher) { h.Algorithm = passwords.Bcrypt }},
+		{"Argon2 time", func(h *passwords.Hasher) { h.Argon2Time = 2 }},
+		{"Argon2 memory", func(h *passwords.Hasher) { h.Argon2Memory = 128 }},
+		{"Argon2 threads", func(h *passwords.Hasher) { h.Argon2Threads = 2 }},
+	}
+
+	hash, err := argon2Hasher().Hash("S2525fs_23523")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := argon2Hasher()
+			tc.change(h)
+			if ok, rehash, err := h.Verify("S2525fs_23523", hash); err != nil || !ok || !rehash {
+				t.Errorf("Want a match needing rehash, got %v, %v, %v", ok, rehash, err)
+			}
+		})
+	}
+
+	t.Run("Bcrypt cost", func(t *testing.T) {
+		hash, err := bcryptHasher().Hash("S2525fs_23523")
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := bcryptHasher()
+		h.BcryptCost++
+		if ok, rehash, err := h.Verify("S2525fs_23523", hash); err != nil || !ok || !rehash {
+			t.Errorf("Want a match needing rehash, got %v, %v, %v", ok, rehash, err)
+		}
+	})
+}
+
+func TestMalformedHashes(t *testing.T) {
+	hashes := []string{
+		"",
+		"plain text",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$",
+		"$argon2id$v=19$m=65536,t=3,p=2$c2FsdHNhbHQ$",
+		"$argon2id$v=19$m=64,t=1,p=1$$a2V5a2V5a2V5",
+		"$argon2id$v=19$m=64,t=0,p=1$c2FsdHNhbHQ$a2V5a2V5a2V5",
+		"$argon2id$v=19$m=64,t=1,p=0$c2FsdHNhbHQ$a2V5a2V5a2V5",
+		"$argon2id$v=19$m=0,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5a2V5",
+		"$argon2id$v=19$m=15,t=1,p=2$c2FsdHNhbHQ$a2V5a2V5a2V5",
+		"$argon2id$v=19$m=64,t=1,p=1$not base64!$a2V5a2V5a2V5",
+		"$argon2id$v=x$m=64,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5a2V5",
+	}
+
+	h := argon2Hasher()
+	for _, hash := range hashes {
+		ok, _, err := h.Verify("S2525fs_23523", hash)
+		if ok || err != passwords.ErrMalformedHash {
+			t.Errorf("%q: want ErrMalformedHash, got %v, %v", hash, ok, err)
+		}
+	}
+}
+
+func TestConfigRanges(t *testing.T) {
+	tt := []struct {
+		key, value string
+	}{
+		{"PAMQ_PASSWORD_ALGORITHM", "md5"},
+		{"PAMQ_PASSWORD_BCRYPT_COST", "3"},
+		{"PAMQ_PASSWORD_BCRYPT_COST", "32"},
+		{"PAMQ_PASSWORD_ARGON2_TIME", "0"},
+		{"PAMQ_PASSWORD_ARGON2_THREADS", "0"},
+		{"PAMQ_PASSWORD_ARGON2_THREADS", "256"},
+		{"PAMQ_PASSWORD_ARGON2_MEMORY", "7"},
+	}
+
+	if _, err := passwords.NewHasherFromConfig(); err != nil {
+		t.Fatalf("Want the defaults to be valid, got %v", err)
+	}
+	for _, tc := range tt {
+		t.Run(tc.key+"="+tc.value, func(t *testing.T) {
+			os.Setenv(tc.key, tc.value)
+			defer os.Unsetenv(tc.key)
+			if _, err := passwords.NewHasherFromConfig(); err == nil {
+				t.Error("Want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// Package ratelimit implements token bucket rate limiting, in memory for a
+// single server or in Postgres when several servers share the limits.
+package ratelimit
+
+import (
+	"database/sql"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Rate allows bursts of Burst requests, refilled by one every Every.
+type Rate struct {
+	Burst int
+	Every time.Duration
+}
+
+func (r Rate) perSecond() float64 {
+	return 1 / r.Every.Seconds()
+}
+
+// retryAfter is how long until a bucket holding tokens has a full token.
+func (r Rate) retryAfter(tokens float64) time.Duration {
+	return time.Duration(math.Ceil((1 - tokens) / r.perSecond() * float64(time.Second)))
+}
+
+type Limiter interface {
+	// Allow takes a token from the bucket of key. When the bucket is empty
+	// it returns false and how long to wait before trying again.
+	Allow(key string) (bool, time.Duration, error)
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// pruneEvery is how many calls to Allow happen between removals of full
+// buckets.
+const pruneEvery = 1000
+
+type MemoryLimiter struct {
+	Rate Rate
+	// Now returns the current time; tests may replace it.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+}
+
+func NewMemoryLimiter(rate Rate) *MemoryLimiter {
+	return &MemoryLimiter{Rate: rate, Now: time.Now, buckets: map[string]*bucket{}}
+}
+
+func (m *MemoryLimiter) refill(b *bucket, now time.Time) {
+	b.tokens = math.Min(float64(m.Rate.Burst), b.tokens+now.Sub(b.updated).Seconds()*m.Rate.perSecond())
+	b.updated = now
+}
+
+func (m *MemoryLimiter) Allow(key string) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.Now()
+	m.calls++
+	if m.calls%pruneEvery == 0 {
+		for k, b := range m.buckets {
+			if m.refill(b, now); b.tokens >= float64(m.Rate.Burst) {
+				delete(m.buckets, k)
+			}
+		}
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(m.Rate.Burst), updated: now}
+		m.buckets[key] = b
+	}
+	m.refill(b, now)
+
+	if b.tokens < 1 {
+		return false, m.Rate.retryAfter(b.tokens), nil
+	}
+	b.tokens--
+	return true, 0, nil
+}
+
+// PostgresLimiter keeps limits in the rate_limit_bucket table. Name is
+// prefixed to keys so that limiters can share the table.
+//
+// Rather than a token count it stores the theoretical arrival time of the
+// next request (GCRA), which behaves as a token bucket but can be updated
+// with a single conditional upsert.
+type PostgresLimiter struct {
+	// calls comes first to be 64-bit aligned for atomic access.
+	calls uint64
+
+	DB   *sql.DB
+	Name string
+	Rate Rate
+}
+
+func NewPostgresLimiter(db *sql.DB, name string, rate Rate) *PostgresLimiter {
+	return &PostgresLimiter{DB: db, Name: name, Rate: rate}
+}
+
+const takeTokenQuery = `INSERT INTO rate_limit_bucket AS b (key, tat) VALUES ($1, NOW() + $2 * INTERVAL '1 microsecond')
+	ON CONFLICT (key) DO UPDATE SET tat = GREATEST(b.tat, NOW()) + $2 * INTERVAL '1 microsecond'
+	WHERE GREATEST(b.tat, NOW()) + $2 * INTERVAL '1 microsecond' <= NOW() + $3 * INTERVAL '1 microsecond'
+	RETURNING tat`
+
+const retryAfterQuery = `SELECT EXTRACT(EPOCH FROM GREATEST(tat, NOW()) + ($2 - $3) * INTERVAL '1 microsecond' - NOW())
+	FROM rate_limit_bucket WHERE key=$1`
+
+// A bucket whose next arrival time has passed is full, like a missing one,
+// so it can be removed. Any limiter may remove those of the others.
+const pruneQuery = `DELETE FROM rate_limit_bucket WHERE tat < NOW()`
+
+func (p *PostgresLimiter) Allow(key string) (bool, time.Duration, error) {
+	if atomic.AddUint64(&p.calls, 1)%pruneEvery == 0 {
+		// A failed prune is retried on the next round.
+		p.DB.Exec(pruneQuery)
+	}
+
+	key = p.Name + ":" + key
+	every := p.Rate.Every.Microseconds()
+	tolerance := every * int64(p.Rate.Burst)
+
+	var tat time.Time
+	err := p.DB.QueryRow(takeTokenQuery, key, every, tolerance).Scan(&tat)
+	if err == nil {
+		return true, 0, nil
+	} else if err != sql.ErrNoRows {
+		return false, 0, err
+	}
+
+	var seconds float64
+	if err := p.DB.QueryRow(retryAfterQuery, key, every, tolerance).Scan(&seconds); err != nil {
+		return false, 0, err
+	}
+	return false, time.Duration(math.Ceil(seconds * float64(time.Second))), nil
+}
@@ -0,0 +1,46 @@
+package ratelimit_test
+
+import (
+	"PamQ/ratelimit"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := ratelimit.NewMemoryLimiter(ratelimit.Rate{Burst: 3, Every: 10 * time.Second})
+	limiter.Now = func() time.Time { return now }
+
+	tt := []struct {
+		name       string
+		key        string
+		wait       time.Duration
+		allowed    bool
+		retryAfter time.Duration
+	}{
+		{name: "First request", key: "a", allowed: true},
+		{name: "Second request", key: "a", allowed: true},
+		{name: "Third request", key: "a", allowed: true},
+		{name: "Burst exhausted", key: "a", allowed: false, retryAfter: 10 * time.Second},
+		{name: "Other key", key: "b", allowed: true},
+		{name: "Partially refilled", key: "a", wait: 4 * time.Second, allowed: false, retryAfter: 6 * time.Second},
+		{name: "Refilled", key: "a", wait: 6 * time.Second, allowed: true},
+		{name: "Empty again", key: "a", allowed: false, retryAfter: 10 * time.Second},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			now = now.Add(tc.wait)
+			allowed, retryAfter, err := limiter.Allow(tc.key)
+			if err != nil {
+				t.Fatalf("Allow: %v", err)
+			}
+			if allowed != tc.allowed {
+				t.Errorf("Want allowed '%t', got '%t'", tc.allowed, allowed)
+			}
+			if retryAfter != tc.retryAfter {
+				t.Errorf("Want retry after '%s', got '%s'", tc.retryAfter, retryAfter)
+			}
+		})
+	}
+}
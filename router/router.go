@@ -0,0 +1,79 @@
+// Package router maps the paths of the API to their handlers.
+package router
+
+import (
+	"PamQ/config"
+	"PamQ/handlers"
+	"PamQ/logging"
+	"PamQ/sessions"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// version is an API version served under /api/<name>.
+type version struct {
+	name   string
+	routes func(api *mux.Router)
+}
+
+// versions are served side by side. A new version registers the routes it
+// changes and then calls the routes of the version it builds on: the first
+// route registered for a path and method wins.
+var versions = []version{
+	{"v1", v1},
+}
+
+// The unversioned /api paths predate versioning. They are kept as aliases of
+// v1 until their sunset, set with PAMQ_LEGACY_API_SUNSET (YYYY-MM-DD).
+var (
+	legacyDeprecation = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+	legacySunset      = parseDate("LEGACY_API_SUNSET")
+)
+
+func parseDate(key string) time.Time {
+	value := config.String(key, "")
+	if len(value) == 0 {
+		return time.Time{}
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		logging.Default.Warn("ignoring invalid date", "key", "PAMQ_"+key, "value", value)
+	}
+	return t
+}
+
+// mount registers routes on api behind the authentication middlewares.
+func mount(api *mux.Router, routes func(api *mux.Router)) {
+	routes(api)
+	api.Use(sessions.Cache)
+	// BearerAuth has to run before CSRFProtect so token requests are exempt
+	// from CSRF checks.
+	api.Use(handlers.BearerAuth)
+	api.Use(handlers.CSRFProtect)
+}
+
+// New returns the router serving every route of the API. Routes added here
+// must also be described in the OpenAPI document. Wrapped in handlers.Metrics
+// it reports the route of each request.
+func New() *mux.Router {
+	r := mux.NewRouter()
+	r.Use(handlers.RecordRoute)
+	r.HandleFunc("/metrics", handlers.MetricsHandler).Methods(http.MethodGet)
+	r.Handle("/healthz", handlers.RootHandler(handlers.HealthzHandler)).Methods(http.MethodGet)
+	r.Handle("/readyz", handlers.RootHandler(handlers.ReadyzHandler)).Methods(http.MethodGet)
+
+	for _, v := range versions {
+		mount(r.PathPrefix("/api/"+v.name).Subrouter(), v.routes)
+	}
+
+	legacy := r.PathPrefix("/api").Subrouter()
+	legacy.Use(handlers.Deprecated(legacyDeprecation, legacySunset, func(path string) string {
+		return "/api/v1" + strings.TrimPrefix(path, "/api")
+	}))
+	mount(legacy, v1)
+
+	return r
+}
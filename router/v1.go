@@ -0,0 +1,60 @@
+package router
+
+import (
+	"PamQ/apitokens"
+	"PamQ/handlers"
+	"PamQ/openapi"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// v1 registers the routes of the first version of the API, which the
+// unversioned /api paths also serve.
+func v1(api *mux.Router) {
+	api.HandleFunc("/openapi.json", openapi.Handler).Methods(http.MethodGet)
+	api.Handle("/csrf", handlers.RootHandler(handlers.CSRFTokenHandler)).Methods(http.MethodGet)
+	api.Handle("/signup", handlers.LimitSignup(handlers.RootHandler(handlers.SignupHandler))).Methods(http.MethodPost)
+	api.Handle("/login", handlers.LimitLogin(handlers.RootHandler(handlers.LoginHandler))).Methods(http.MethodPost)
+	api.Handle("/login/2fa", handlers.LimitLogin(handlers.RootHandler(handlers.LoginTwoFactorHandler))).Methods(http.MethodPost)
+	api.Handle("/logout", handlers.RootHandler(handlers.LogoutHandler)).Methods(http.MethodPost)
+	api.Handle("/oidc/{provider}/login", handlers.RootHandler(handlers.OIDCLoginHandler)).Methods(http.MethodGet)
+	api.Handle("/oidc/{provider}/callback", handlers.RootHandler(handlers.OIDCCallbackHandler)).Methods(http.MethodGet)
+	api.Handle("/logout/all", handlers.RootHandler(handlers.LogoutAllHandler)).Methods(http.MethodPost)
+	api.Handle("/sessions", handlers.RootHandler(handlers.ListSessionsHandler)).Methods(http.MethodGet)
+	api.Handle("/sessions/{sessionID}", handlers.RootHandler(handlers.RevokeSessionHandler)).Methods(http.MethodDelete)
+	api.Handle("/me", handlers.RootHandler(handlers.MeHandler)).Methods(http.MethodGet, http.MethodPatch)
+	api.Handle("/me", handlers.RootHandler(handlers.DeleteAccountHandler)).Methods(http.MethodDelete)
+	api.Handle("/2fa/setup", handlers.RootHandler(handlers.TwoFactorSetupHandler)).Methods(http.MethodPost)
+	api.Handle("/2fa/enable", handlers.RootHandler(handlers.TwoFactorEnableHandler)).Methods(http.MethodPost)
+	api.Handle("/2fa/disable", handlers.RootHandler(handlers.TwoFactorDisableHandler)).Methods(http.MethodPost)
+	api.Handle("/2fa/recovery-codes", handlers.RootHandler(handlers.RecoveryCodesHandler)).Methods(http.MethodGet, http.MethodPost)
+	api.Handle("/verify-email", handlers.RootHandler(handlers.VerifyEmailHandler)).Methods(http.MethodPost)
+	api.Handle("/verify-email/resend", handlers.RootHandler(handlers.ResendVerificationHandler)).Methods(http.MethodPost)
+	api.Handle("/password/forgot", handlers.LimitPasswordReset(handlers.RootHandler(handlers.ForgotPasswordHandler))).Methods(http.MethodPost)
+	api.Handle("/password/reset", handlers.RootHandler(handlers.ResetPasswordHandler)).Methods(http.MethodPost)
+
+	api.Handle("/tokens", handlers.RootHandler(handlers.TokensHandler)).Methods(http.MethodGet, http.MethodPost)
+	api.Handle("/tokens/{tokenID}", handlers.RootHandler(handlers.RevokeTokenHandler)).Methods(http.MethodDelete)
+
+	api.Handle("/categories", handlers.RootHandler(handlers.CategoriesHandler)).Methods(http.MethodGet, http.MethodPost)
+	api.Handle("/categories/{categoryID}", handlers.RootHandler(handlers.CategoryHandler)).Methods(http.MethodPatch, http.MethodDelete)
+
+	// Routes wrapped in WithScope or WithPublicScope also accept API tokens
+	// having that scope.
+	quiz := api.PathPrefix("/quiz").Subrouter()
+	quiz.Handle("/create", handlers.WithScope(apitokens.QuizWrite, handlers.CreateQuizHandler)).Methods(http.MethodPost)
+	quiz.Handle("/all", handlers.WithPublicScope(apitokens.QuizRead, handlers.ListOfQuizesHandler)).Methods(http.MethodGet)
+	quiz.Handle("/results", handlers.WithScope(apitokens.ResultsRead, handlers.QuizResultsHandler)).Methods(http.MethodGet)
+	quiz.Handle("/{quizID}", handlers.WithPublicScope(apitokens.QuizRead, handlers.QuizHandler)).Methods(http.MethodGet)
+	quiz.Handle("/{quizID}", handlers.LimitSubmission(handlers.WithScope(apitokens.QuizSubmit, handlers.QuizHandler))).Methods(http.MethodPost)
+	quiz.Handle("/{quizID}", handlers.WithScope(apitokens.QuizWrite, handlers.EditQuizHandler)).Methods(http.MethodPut)
+	quiz.Handle("/{quizID}/collaborators", handlers.WithScope(apitokens.QuizWrite, handlers.CollaboratorsHandler)).Methods(http.MethodGet, http.MethodPost)
+	quiz.Handle("/{quizID}/collaborators/{username}", handlers.WithScope(apitokens.QuizWrite, handlers.RemoveCollaboratorHandler)).Methods(http.MethodDelete)
+	quiz.Handle("/{quizID}/audit", handlers.WithScope(apitokens.QuizRead, handlers.QuizAuditHandler)).Methods(http.MethodGet)
+	quiz.Handle("/{quizID}/participations", handlers.WithScope(apitokens.ResultsRead, handlers.QuizParticipationsHandler)).Methods(http.MethodGet)
+	quiz.Handle("/{quizID}/leaderboard", handlers.WithPublicScope(apitokens.QuizRead, handlers.LeaderboardHandler)).Methods(http.MethodGet)
+	quiz.Handle("/{quizID}/analysis", handlers.WithScope(apitokens.ResultsRead, handlers.QuizAnalysisHandler)).Methods(http.MethodGet)
+	quiz.Handle("/{quizID}/participations/export", handlers.WithScope(apitokens.ResultsRead, handlers.ExportParticipationsHandler)).Methods(http.MethodGet)
+	quiz.Handle("/{quizID}/participations/{participationID}/grade", handlers.WithScope(apitokens.ResultsGrade, handlers.GradeParticipationHandler)).Methods(http.MethodPost)
+}
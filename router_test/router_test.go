@@ -0,0 +1,232 @@
+package router_test
+
+import (
+	"PamQ/handlers"
+	"PamQ/openapi"
+	"PamQ/router"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func routeOperations(t *testing.T, r *mux.Router) []string {
+	var list []string
+	err := r.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// Path prefixes of subrouters don't have methods.
+			return nil
+		}
+		for _, m := range methods {
+			list = append(list, m+" "+path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func difference(a, b []string) []string {
+	in := map[string]bool{}
+	for _, s := range b {
+		in[s] = true
+	}
+	var diff []string
+	for _, s := range a {
+		if !in[s] {
+			diff = append(diff, s)
+		}
+	}
+	return diff
+}
+
+// versioned matches the paths of a version of the API. Other /api paths are
+// aliases of v1.
+var versioned = regexp.MustCompile(`^/api/v[0-9]+/`)
+
+func v1Path(path string) string {
+	if strings.HasPrefix(path, "/api/") && !versioned.MatchString(path) {
+		return "/api/v1/" + strings.TrimPrefix(path, "/api/")
+	}
+	return path
+}
+
+func TestRoutesDocumented(t *testing.T) {
+	var routes, aliases []string
+	for _, op := range routeOperations(t, router.New()) {
+		if i := strings.Index(op, " "); v1Path(op[i+1:]) != op[i+1:] {
+			aliases = append(aliases, op[:i+1]+v1Path(op[i+1:]))
+		} else {
+			routes = append(routes, op)
+		}
+	}
+	documented := openapi.Spec.Operations()
+
+	for _, op := range difference(aliases, routes) {
+		t.Errorf("alias of %s has no v1 route", op)
+	}
+	for _, op := range difference(routes, documented) {
+		t.Errorf("route %s is missing from the OpenAPI document", op)
+	}
+	for _, op := range difference(documented, routes) {
+		t.Errorf("operation %s has no route", op)
+	}
+}
+
+func csrfToken(t *testing.T, r *mux.Router) string {
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/csrf", nil))
+	var body struct {
+		Token string `json:"csrf_token"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	return body.Token
+}
+
+// These requests are answered without a database, so their responses can
+// be checked here.
+func TestResponsesMatchSpec(t *testing.T) {
+	r := router.New()
+	token := csrfToken(t, r)
+
+	tt := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		csrf       bool
+		status     int
+		deprecated bool
+	}{
+		{name: "Health", method: http.MethodGet, target: "/healthz", status: http.StatusOK},
+		{name: "Metrics", method: http.MethodGet, target: "/metrics", status: http.StatusOK},
+		{name: "OpenAPI document", method: http.MethodGet, target: "/api/v1/openapi.json", status: http.StatusOK},
+		{name: "CSRF token", method: http.MethodGet, target: "/api/v1/csrf", status: http.StatusOK},
+		{name: "Not logged in", method: http.MethodGet, target: "/api/v1/me", status: http.StatusUnauthorized},
+		{name: "Invalid query", method: http.MethodGet, target: "/api/v1/quiz/all?sort=random", status: http.StatusBadRequest},
+		{name: "Missing CSRF token", method: http.MethodPost, target: "/api/v1/login", body: `{}`, status: http.StatusForbidden},
+		{name: "Invalid JSON", method: http.MethodPost, target: "/api/v1/signup", body: `{`, csrf: true, status: http.StatusBadRequest},
+		{name: "Invalid field", method: http.MethodPost, target: "/api/v1/signup", csrf: true, status: http.StatusBadRequest,
+			body: `{"username": "a green crocodile", "password": "S2525fs_23523", "password_confirm": "S2525fs_23523", "email": "sab@oo.com"}`},
+		{name: "Deprecated alias", method: http.MethodGet, target: "/api/quiz/all?sort=random", status: http.StatusBadRequest, deprecated: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if tc.csrf {
+				request.AddCookie(&http.Cookie{Name: handlers.CSRFCookieName, Value: token})
+				request.Header.Set(handlers.CSRFHeaderName, token)
+			}
+
+			var match mux.RouteMatch
+			if !r.Match(request, &match) {
+				t.Fatalf("%s %s matches no route", tc.method, tc.target)
+			}
+			path, err := match.Route.GetPathTemplate()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, request)
+			if rec.Code != tc.status {
+				t.Fatalf("Want status '%d', got '%d': %s", tc.status, rec.Code, rec.Body)
+			}
+			if deprecated := len(rec.Header().Get("Deprecation")) != 0; deprecated != tc.deprecated {
+				t.Errorf("Want deprecated %v, got Deprecation header '%s'", tc.deprecated, rec.Header().Get("Deprecation"))
+			}
+			if tc.deprecated && !strings.Contains(rec.Header().Get("Link"), "</api/v1/quiz/all>") {
+				t.Errorf("Want a link to the successor, got '%s'", rec.Header().Get("Link"))
+			}
+			err = openapi.Spec.ValidateResponse(tc.method, v1Path(path), rec.Code, rec.Header().Get("Content-Type"), rec.Body.Bytes())
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// The schemas of the models must keep up with their Go types.
+func TestSchemasMatchTypes(t *testing.T) {
+	now := time.Now()
+	categoryID := 3
+	question := handlers.Question{Id: 1, QType: handlers.MultiChoice, Statement: "2 + 2?",
+		Option1: "3", Option2: "4", Option3: "5", Option4: "22", Answer: "2"}
+	quiz := handlers.Quiz{
+		Id:                    7,
+		Creator:               "sara",
+		Name:                  "Arithmetic",
+		Description:           "Sums",
+		Tags:                  []string{"math"},
+		CategoryID:            &categoryID,
+		Questions:             []handlers.Question{question},
+		GradingType:           handlers.WithNegetiveMark,
+		PassFail:              true,
+		PassingScore:          50,
+		AllowedParticipations: 2,
+		OpensAt:               &now,
+		DateCreated:           handlers.JSONTime(now),
+	}
+
+	tt := []struct {
+		schema string
+		value  interface{}
+	}{
+		{"NewUser", handlers.NewUser{Username: "sara", Email: "sara@example.com", Password: "S2525fs_23523", PasswordConfirm: "S2525fs_23523"}},
+		{"NewQuiz", handlers.NewQuiz{
+			Name: "Arithmetic",
+			Tags: []string{"math"},
+			Questions: []handlers.NewQuestion{{
+				Type:    handlers.ShortAnswer,
+				Payload: &handlers.ShortAnswerQuestion{Type: handlers.ShortAnswer, Statement: "2 + 2?", Answer: "4"},
+			}},
+			GradingType: handlers.OnlyCorrect,
+			ClosesAt:    &now,
+		}},
+		{"Quiz", quiz},
+		{"QuizListItem", handlers.QuizListItem{Quiz: quiz, Participations: 4}},
+		{"QuizParticipation", handlers.QuizParticipation{
+			ID:          1,
+			QuizID:      7,
+			Username:    "sara",
+			Result:      "Passed",
+			Score:       75,
+			PassFail:    true,
+			DateCreated: handlers.JSONTime(now),
+			Answers:     []handlers.ParticipationAnswer{{QuestionID: 1, Answer: "2", Result: handlers.Correct, Mark: 1}},
+		}},
+		{"User", handlers.User{Username: "sara", Email: "sara@example.com", DateCreated: handlers.JSONTime(now)}},
+		{"Category", handlers.Category{ID: 1, Name: "Science", Children: []*handlers.Category{
+			{ID: 2, ParentID: new(int), Name: "Physics", Children: []*handlers.Category{}},
+		}}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.schema, func(t *testing.T) {
+			js, err := json.Marshal(tc.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := openapi.Spec.ValidateSchema(tc.schema, js); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package sessions
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PostgresStore keeps sessions in the user_session table.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (p *PostgresStore) Create(s *Session) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Expired sessions are cleaned up whenever their user logs in again.
+	if _, err := tx.Exec(`DELETE FROM user_session WHERE username=$1 AND expires_at < NOW()`, s.Username); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO user_session (id, username, user_agent, ip, date_created, last_seen, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		s.ID, s.Username, s.UserAgent, s.IP, s.Created, s.LastSeen, s.Expires); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const sessionColumns = `id, username, COALESCE(user_agent, ''), COALESCE(ip, ''), date_created, last_seen, expires_at`
+
+func scanSession(row interface{ Scan(...interface{}) error }) (*Session, error) {
+	var s Session
+	err := row.Scan(&s.ID, &s.Username, &s.UserAgent, &s.IP, &s.Created, &s.LastSeen, &s.Expires)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	return &s, err
+}
+
+func (p *PostgresStore) Get(id string) (*Session, error) {
+	return scanSession(p.db.QueryRow(`SELECT `+sessionColumns+` FROM user_session WHERE id=$1`, id))
+}
+
+func (p *PostgresStore) Touch(id string, lastSeen time.Time) error {
+	_, err := p.db.Exec(`UPDATE user_session SET last_seen=$2 WHERE id=$1`, id, lastSeen)
+	return err
+}
+
+func (p *PostgresStore) Delete(id string) error {
+	_, err := p.db.Exec(`DELETE FROM user_session WHERE id=$1`, id)
+	return err
+}
+
+func (p *PostgresStore) DeleteUser(username, except string) error {
+	_, err := p.db.Exec(`DELETE FROM user_session WHERE username=$1 AND id<>$2`, username, except)
+	return err
+}
+
+func (p *PostgresStore) List(username string) ([]Session, error) {
+	rows, err := p.db.Query(`SELECT `+sessionColumns+` FROM user_session WHERE username=$1 AND expires_at > NOW() ORDER BY last_seen DESC`, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []Session{}
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *s)
+	}
+	return list, rows.Err()
+}
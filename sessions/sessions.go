@@ -1,50 +1,250 @@
 package sessions
 
 import (
+	"PamQ/clientip"
+	"PamQ/config"
+	db "PamQ/database"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"net/http"
+	"time"
+)
+
+const CookieName = "session"
 
-	"github.com/gorilla/sessions"
+var (
+	// Store holds the sessions; tests may replace it with a MemoryStore.
+	Store SessionStore = NewPostgresStore(db.DB)
+
+	Lifetime     = config.Duration("SESSION_LIFETIME", 7*24*time.Hour)
+	IdleTimeout  = config.Duration("SESSION_IDLE_TIMEOUT", 24*time.Hour)
+	SecureCookie = config.Bool("SESSION_SECURE_COOKIE", false)
 )
 
-var Store = sessions.NewCookieStore([]byte("pass")) //TODO use env variable
+// touchInterval limits how often last_seen is written for an active session.
+const touchInterval = time.Minute
+
+// The cookie holds a random token and only its hash is stored, so a leaked
+// session table can't be used to log in.
+func sessionID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func setCookie(w http.ResponseWriter, value string, expires time.Time) {
+	cookie := &http.Cookie{
+		Name:     CookieName,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   SecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	}
+	if len(value) == 0 {
+		cookie.MaxAge = -1
+	} else {
+		cookie.Expires = expires
+	}
+	http.SetCookie(w, cookie)
+}
+
+type cacheKey struct{}
+
+// cache holds the session of a request once it has been looked up.
+type cache struct {
+	resolved bool
+	session  *Session
+}
+
+// WithCache returns r with room for its session, so that it is looked up in
+// the store at most once however often handlers ask for it.
+func WithCache(r *http.Request) *http.Request {
+	if _, ok := r.Context().Value(cacheKey{}).(*cache); ok {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), cacheKey{}, &cache{}))
+}
+
+// Cache is a middleware applying WithCache.
+func Cache(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, WithCache(r))
+	})
+}
+
+func setCached(r *http.Request, s *Session) {
+	if c, ok := r.Context().Value(cacheKey{}).(*cache); ok {
+		c.resolved, c.session = true, s
+	}
+}
+
+// Current returns the valid session of the request, if any. Requests made
+// with an API token have no session.
+func Current(r *http.Request) (*Session, bool) {
+	if IsTokenRequest(r) {
+		return nil, false
+	}
+	if c, ok := r.Context().Value(cacheKey{}).(*cache); ok && c.resolved {
+		return c.session, c.session != nil
+	}
+	s, ok := lookup(r)
+	setCached(r, s)
+	return s, ok
+}
+
+// Cached returns the session of the request if it has already been looked
+// up, without going to the store.
+func Cached(r *http.Request) (*Session, bool) {
+	if c, ok := r.Context().Value(cacheKey{}).(*cache); ok && c.resolved {
+		return c.session, c.session != nil
+	}
+	return nil, false
+}
+
+func lookup(r *http.Request) (*Session, bool) {
+	cookie, err := r.Cookie(CookieName)
+	if err != nil || len(cookie.Value) == 0 {
+		return nil, false
+	}
+
+	id := sessionID(cookie.Value)
+	s, err := Store.Get(id)
+	if err != nil {
+		return nil, false
+	}
+
+	now := time.Now()
+	if now.After(s.Expires) || now.Sub(s.LastSeen) > IdleTimeout {
+		Store.Delete(id)
+		return nil, false
+	}
+	if now.Sub(s.LastSeen) > touchInterval {
+		if err := Store.Touch(id, now); err == nil {
+			s.LastSeen = now
+		}
+	}
+	s.Current = true
+	return s, true
+}
 
 func IsLoggedIn(r *http.Request) bool {
-	session, _ := Store.Get(r, "session")
-	if session.Values["loggedin"] == true {
-		return true
+	if id, ok := token(r); ok {
+		return id.granted
 	}
-	return false
+	_, ok := Current(r)
+	return ok
 }
 
+// Login starts a new session for username, replacing the current one.
 func Login(w http.ResponseWriter, r *http.Request, username string) error {
-	session, err := Store.Get(r, "session")
-	if err == nil && !IsLoggedIn(r) {
-		session.Values["loggedin"] = true
-		session.Values["username"] = username
-		session.Save(r, w)
-		return nil
+	if s, ok := Current(r); ok {
+		Store.Delete(s.ID)
 	}
-	return err
+
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+	userAgent := r.UserAgent()
+	if len(userAgent) > 500 {
+		userAgent = userAgent[:500]
+	}
+
+	now := time.Now()
+	s := &Session{
+		ID:        sessionID(token),
+		Username:  username,
+		UserAgent: userAgent,
+		IP:        clientip.FromRequest(r),
+		Created:   now,
+		LastSeen:  now,
+		Expires:   now.Add(Lifetime),
+	}
+	if err := Store.Create(s); err != nil {
+		return err
+	}
+	setCookie(w, token, s.Expires)
+	s.Current = true
+	setCached(r, s)
+	return nil
 }
 
 func Logout(w http.ResponseWriter, r *http.Request) error {
-	session, err := Store.Get(r, "session")
-	if err == nil && session.Values["loggedin"] == true {
-		session.Values["loggedin"] = false
-		session.Save(r, w)
+	s, ok := Current(r)
+	setCookie(w, "", time.Time{})
+	if !ok {
+		return nil
 	}
-	return err
+	setCached(r, nil)
+	return Store.Delete(s.ID)
 }
 
-func GetUsername(r *http.Request) (string, bool) {
-	session, _ := Store.Get(r, "session")
-	if session.Values["loggedin"] == true {
-		val := session.Values["username"]
-		username, ok := val.(string)
-		if !ok {
-			return "", false
+// LogoutAll ends every session of username, including the current one.
+func LogoutAll(w http.ResponseWriter, username string) error {
+	setCookie(w, "", time.Time{})
+	return Store.DeleteUser(username, "")
+}
+
+// LogoutOthers ends every session of username except the current one.
+func LogoutOthers(r *http.Request, username string) error {
+	except := ""
+	if s, ok := Current(r); ok {
+		except = s.ID
+	}
+	return Store.DeleteUser(username, except)
+}
+
+// Revoke ends the session with the given ID if it belongs to username.
+func Revoke(username, id string) error {
+	s, err := Store.Get(id)
+	if err != nil {
+		return err
+	}
+	if s.Username != username {
+		return ErrNotFound
+	}
+	return Store.Delete(id)
+}
+
+// List returns the active sessions of username, marking the one of the
+// request as current.
+func List(r *http.Request, username string) ([]Session, error) {
+	list, err := Store.List(username)
+	if err != nil {
+		return nil, err
+	}
+
+	current, _ := Current(r)
+	active := []Session{}
+	now := time.Now()
+	for _, s := range list {
+		if now.After(s.Expires) || now.Sub(s.LastSeen) > IdleTimeout {
+			continue
 		}
-		return username, true
+		s.Current = current != nil && s.ID == current.ID
+		active = append(active, s)
+	}
+	return active, nil
+}
+
+func GetUsername(r *http.Request) (string, bool) {
+	if id, ok := token(r); ok {
+		return id.username, id.granted
+	}
+	s, ok := Current(r)
+	if !ok {
+		return "", false
 	}
-	return "", false
+	return s.Username, true
 }
@@ -0,0 +1,105 @@
+package sessions
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrNotFound = errors.New("sessions: session not found")
+
+type Session struct {
+	ID        string    `json:"id"`
+	Username  string    `json:"-"`
+	UserAgent string    `json:"user_agent"`
+	IP        string    `json:"ip"`
+	Created   time.Time `json:"date_created"`
+	LastSeen  time.Time `json:"last_seen"`
+	Expires   time.Time `json:"expires_at"`
+	Current   bool      `json:"current"`
+}
+
+// SessionStore persists sessions by ID. Expiry is enforced by the callers
+// in this package, stores only keep the data.
+type SessionStore interface {
+	Create(s *Session) error
+	Get(id string) (*Session, error)
+	Touch(id string, lastSeen time.Time) error
+	Delete(id string) error
+	// DeleteUser deletes every session of username except the one with ID
+	// except, which may be empty.
+	DeleteUser(username, except string) error
+	List(username string) ([]Session, error)
+}
+
+// MemoryStore keeps sessions in process memory. It is meant for tests and
+// single instance development servers.
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: map[string]Session{}}
+}
+
+func (m *MemoryStore) Create(s *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[s.ID] = *s
+	return nil
+}
+
+func (m *MemoryStore) Get(id string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &s, nil
+}
+
+func (m *MemoryStore) Touch(id string, lastSeen time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return ErrNotFound
+	}
+	s.LastSeen = lastSeen
+	m.sessions[id] = s
+	return nil
+}
+
+func (m *MemoryStore) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *MemoryStore) DeleteUser(username, except string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, s := range m.sessions {
+		if s.Username == username && id != except {
+			delete(m.sessions, id)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) List(username string) ([]Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := []Session{}
+	for _, s := range m.sessions {
+		if s.Username == username {
+			list = append(list, s)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].LastSeen.After(list[j].LastSeen) })
+	return list, nil
+}
@@ -0,0 +1,51 @@
+package sessions
+
+import (
+	"context"
+	"net/http"
+)
+
+type tokenKey struct{}
+
+// tokenIdentity is attached to requests authenticated with an API token.
+// It only counts as logged in once a handler has granted one of its scopes.
+type tokenIdentity struct {
+	username string
+	scopes   []string
+	granted  bool
+}
+
+// WithToken returns r authenticated as username with the given token scopes.
+func WithToken(r *http.Request, username string, scopes []string) *http.Request {
+	id := &tokenIdentity{username: username, scopes: scopes}
+	return r.WithContext(context.WithValue(r.Context(), tokenKey{}, id))
+}
+
+func token(r *http.Request) (*tokenIdentity, bool) {
+	id, ok := r.Context().Value(tokenKey{}).(*tokenIdentity)
+	return id, ok
+}
+
+// IsTokenRequest reports whether r is authenticated with an API token rather
+// than a session cookie.
+func IsTokenRequest(r *http.Request) bool {
+	_, ok := token(r)
+	return ok
+}
+
+// Grant returns r with its token identity active if the token has scope.
+// Requests authenticated with a session cookie are returned unchanged.
+func Grant(r *http.Request, scope string) (*http.Request, bool) {
+	id, ok := token(r)
+	if !ok {
+		return r, true
+	}
+	for _, s := range id.scopes {
+		if s == scope {
+			granted := *id
+			granted.granted = true
+			return r.WithContext(context.WithValue(r.Context(), tokenKey{}, &granted)), true
+		}
+	}
+	return r, false
+}
@@ -0,0 +1,168 @@
+package sessions_test
+
+import (
+	"PamQ/sessions"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// login returns a request carrying the session cookie of a new login.
+func login(t *testing.T, username string) *http.Request {
+	recorder := httptest.NewRecorder()
+	if err := sessions.Login(recorder, httptest.NewRequest(http.MethodPost, "/api/login", nil), username); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range recorder.Result().Cookies() {
+		request.AddCookie(c)
+	}
+	return request
+}
+
+func TestSessions(t *testing.T) {
+	sessions.Store = sessions.NewMemoryStore()
+
+	tt := []struct {
+		name     string
+		action   func(t *testing.T, r *http.Request)
+		loggedIn bool
+	}{
+		{
+			name:     "Login",
+			action:   func(t *testing.T, r *http.Request) {},
+			loggedIn: true,
+		},
+		{
+			name: "Logout",
+			action: func(t *testing.T, r *http.Request) {
+				sessions.Logout(httptest.NewRecorder(), r)
+			},
+			loggedIn: false,
+		},
+		{
+			name: "Logout all devices",
+			action: func(t *testing.T, r *http.Request) {
+				other := login(t, "test_user")
+				sessions.LogoutAll(httptest.NewRecorder(), "test_user")
+				if sessions.IsLoggedIn(other) {
+					t.Errorf("Other session still logged in")
+				}
+			},
+			loggedIn: false,
+		},
+		{
+			name: "Revoke other session",
+			action: func(t *testing.T, r *http.Request) {
+				other := login(t, "test_user")
+				s, _ := sessions.Current(other)
+				if err := sessions.Revoke("someone_else", s.ID); err != sessions.ErrNotFound {
+					t.Errorf("Want '%v' revoking another user's session, got '%v'", sessions.ErrNotFound, err)
+				}
+				if err := sessions.Revoke("test_user", s.ID); err != nil {
+					t.Errorf("Revoke: %v", err)
+				}
+				if sessions.IsLoggedIn(other) {
+					t.Errorf("Revoked session still logged in")
+				}
+			},
+			loggedIn: true,
+		},
+		{
+			name: "Idle timeout",
+			action: func(t *testing.T, r *http.Request) {
+				s, _ := sessions.Current(r)
+				sessions.Store.Touch(s.ID, time.Now().Add(-sessions.IdleTimeout-time.Minute))
+			},
+			loggedIn: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			request := login(t, "test_user")
+			tc.action(t, request)
+			if got := sessions.IsLoggedIn(request); got != tc.loggedIn {
+				t.Errorf("Want logged in '%t', got '%t'", tc.loggedIn, got)
+			}
+			if username, ok := sessions.GetUsername(request); ok && username != "test_user" {
+				t.Errorf("Want username 'test_user', got '%s'", username)
+			}
+		})
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	sessions.Store = sessions.NewMemoryStore()
+
+	request := login(t, "test_user")
+	login(t, "test_user")
+	login(t, "other_user")
+
+	list, err := sessions.List(request, "test_user")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("Want 2 sessions, got %d", len(list))
+	}
+	current := 0
+	for _, s := range list {
+		if s.Current {
+			current++
+		}
+	}
+	if current != 1 {
+		t.Errorf("Want 1 current session, got %d", current)
+	}
+}
+
+// countingStore counts the lookups and writes of a request's session.
+type countingStore struct {
+	*sessions.MemoryStore
+	gets, touches int
+}
+
+func (c *countingStore) Get(id string) (*sessions.Session, error) {
+	c.gets++
+	return c.MemoryStore.Get(id)
+}
+
+func (c *countingStore) Touch(id string, lastSeen time.Time) error {
+	c.touches++
+	return c.MemoryStore.Touch(id, lastSeen)
+}
+
+func TestSessionCache(t *testing.T) {
+	store := &countingStore{MemoryStore: sessions.NewMemoryStore()}
+	sessions.Store = store
+	request := login(t, "test_user")
+	s, _ := sessions.Current(request)
+	store.Touch(s.ID, time.Now().Add(-time.Hour))
+	store.gets, store.touches = 0, 0
+
+	request = sessions.WithCache(request)
+	if _, ok := sessions.Cached(request); ok || store.gets != 0 {
+		t.Fatal("Want no session cached before the first lookup")
+	}
+	for i := 0; i < 3; i++ {
+		if !sessions.IsLoggedIn(request) {
+			t.Fatal("Want logged in")
+		}
+		if username, _ := sessions.GetUsername(request); username != "test_user" {
+			t.Fatalf("Want username 'test_user', got '%s'", username)
+		}
+	}
+	if store.gets != 1 || store.touches != 1 {
+		t.Errorf("Want 1 lookup and 1 touch, got %d and %d", store.gets, store.touches)
+	}
+	if cached, ok := sessions.Cached(request); !ok || cached.Username != "test_user" {
+		t.Errorf("Want the cached session of 'test_user', got %+v", cached)
+	}
+
+	sessions.Logout(httptest.NewRecorder(), request)
+	if sessions.IsLoggedIn(request) {
+		t.Error("Want logged out in the rest of the request")
+	}
+}
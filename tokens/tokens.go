@@ -0,0 +1,114 @@
+// Package tokens creates signed, expiring tokens for links sent to users,
+// such as email verification and password reset.
+//
+// A token is bound to a purpose and to a caller supplied binding value which
+// is signed but not included in the token. Binding a reset token to the
+// current password hash, for example, makes it single use.
+package tokens
+
+import (
+	"PamQ/config"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalid = errors.New("tokens: invalid token")
+	ErrExpired = errors.New("tokens: token expired")
+)
+
+type payload struct {
+	Subject string `json:"sub"`
+	Expiry  int64  `json:"exp"`
+}
+
+type Signer struct {
+	key []byte
+}
+
+// Default signs with PAMQ_TOKEN_SECRET. Without it a random key is used, and
+// tokens stop working when the server restarts.
+var Default = newDefaultSigner()
+
+func newDefaultSigner() *Signer {
+	secret := config.String("TOKEN_SECRET", "")
+	if len(secret) != 0 {
+		return NewSigner([]byte(secret))
+	}
+	log.Println("PAMQ_TOKEN_SECRET is not set, using a random key")
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatal(err)
+	}
+	return NewSigner(key)
+}
+
+func NewSigner(key []byte) *Signer {
+	return &Signer{key: key}
+}
+
+func (s *Signer) mac(purpose, encodedPayload, binding string) []byte {
+	m := hmac.New(sha256.New, s.key)
+	m.Write([]byte(purpose))
+	m.Write([]byte{0})
+	m.Write([]byte(encodedPayload))
+	m.Write([]byte{0})
+	m.Write([]byte(binding))
+	return m.Sum(nil)
+}
+
+// Sign returns a token for subject that is valid for ttl.
+func (s *Signer) Sign(purpose, subject, binding string, ttl time.Duration) string {
+	js, _ := json.Marshal(payload{Subject: subject, Expiry: time.Now().Add(ttl).Unix()})
+	encoded := base64.RawURLEncoding.EncodeToString(js)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.mac(purpose, encoded, binding))
+}
+
+func parse(token string) (string, []byte, payload, error) {
+	var p payload
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return "", nil, p, ErrInvalid
+	}
+	js, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", nil, p, ErrInvalid
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, p, ErrInvalid
+	}
+	if err := json.Unmarshal(js, &p); err != nil {
+		return "", nil, p, ErrInvalid
+	}
+	return parts[0], sig, p, nil
+}
+
+// Subject returns the subject of token without verifying it, so the caller
+// can look up the binding to verify it with.
+func Subject(token string) (string, error) {
+	_, _, p, err := parse(token)
+	return p.Subject, err
+}
+
+// Verify checks the signature and expiry of token and returns its subject.
+func (s *Signer) Verify(token, purpose, binding string) (string, error) {
+	encoded, sig, p, err := parse(token)
+	if err != nil {
+		return "", err
+	}
+	if !hmac.Equal(sig, s.mac(purpose, encoded, binding)) {
+		return "", ErrInvalid
+	}
+	if time.Now().Unix() > p.Expiry {
+		return "", ErrExpired
+	}
+	return p.Subject, nil
+}
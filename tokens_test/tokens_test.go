@@ -0,0 +1,53 @@
+package tokens_test
+
+import (
+	"PamQ/tokens"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	s := tokens.NewSigner([]byte("secret"))
+	token := s.Sign("reset", "sara", "hash", time.Hour)
+
+	subject, err := s.Verify(token, "reset", "hash")
+	if err != nil || subject != "sara" {
+		t.Fatalf("Want subject 'sara', got '%s', %v", subject, err)
+	}
+	if subject, err := tokens.Subject(token); err != nil || subject != "sara" {
+		t.Errorf("Want unverified subject 'sara', got '%s', %v", subject, err)
+	}
+}
+
+func TestRejected(t *testing.T) {
+	s := tokens.NewSigner([]byte("secret"))
+	token := s.Sign("reset", "sara", "hash", time.Hour)
+	parts := strings.Split(token, ".")
+	forged := tokens.NewSigner([]byte("other")).Sign("reset", "ali", "hash", time.Hour)
+
+	tt := []struct {
+		name    string
+		token   string
+		purpose string
+		binding string
+		err     error
+	}{
+		{"Wrong purpose", token, "verify", "hash", tokens.ErrInvalid},
+		{"Wrong binding", token, "reset", "new hash", tokens.ErrInvalid},
+		{"Other key", forged, "reset", "hash", tokens.ErrInvalid},
+		{"Swapped payload", strings.Split(forged, ".")[0] + "." + parts[1], "reset", "hash", tokens.ErrInvalid},
+		{"Tampered signature", parts[0] + "." + strings.ToUpper(parts[1]), "reset", "hash", tokens.ErrInvalid},
+		{"Missing signature", parts[0], "reset", "hash", tokens.ErrInvalid},
+		{"Not base64", "a!b.c", "reset", "hash", tokens.ErrInvalid},
+		{"Expired", s.Sign("reset", "sara", "hash", -time.Minute), "reset", "hash", tokens.ErrExpired},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if subject, err := s.Verify(tc.token, tc.purpose, tc.binding); err != tc.err {
+				t.Errorf("Want %v, got '%s', %v", tc.err, subject, err)
+			}
+		})
+	}
+}
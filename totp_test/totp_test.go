@@ -0,0 +1,65 @@
+package totp_test
+
+import (
+	"PamQ/totp"
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The SHA-1 test vectors of RFC 6238, truncated to 6 digits.
+func TestCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	tt := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tc := range tt {
+		got, err := totp.Code(secret, time.Unix(tc.unix, 0))
+		if err != nil {
+			t.Fatalf("Code: %v", err)
+		}
+		if got != tc.want {
+			t.Errorf("At %d want code '%s', got '%s'", tc.unix, tc.want, got)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	now := time.Now()
+	code, _ := totp.Code(secret, now)
+
+	if step, ok := totp.Validate(secret, code, now); !ok || step != totp.Step(now) {
+		t.Errorf("Current code rejected")
+	}
+	if _, ok := totp.Validate(secret, code, now.Add(totp.Period)); !ok {
+		t.Errorf("Code of previous step rejected")
+	}
+	if _, ok := totp.Validate(secret, code, now.Add(3*totp.Period)); ok {
+		t.Errorf("Stale code accepted")
+	}
+	if _, ok := totp.Validate(secret, "12345", now); ok {
+		t.Errorf("Short code accepted")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := totp.ProvisioningURI("PamQ", "alice", "JBSWY3DPEHPK3PXP")
+	if !strings.HasPrefix(uri, "otpauth://totp/PamQ:alice?") || !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") {
+		t.Errorf("Unexpected URI '%s'", uri)
+	}
+}
@@ -0,0 +1,110 @@
+package xlsx_test
+
+import (
+	"PamQ/xlsx"
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sheet writes rows into a workbook and returns its sheet XML.
+func sheet(t *testing.T, rows ...[]interface{}) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := xlsx.NewWriter(&buf, "Results & more")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err := w.WriteRow(row...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(b)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		body, ok := files[name]
+		if !ok {
+			t.Fatalf("%s is missing", name)
+		}
+		wellFormed(t, name, body)
+	}
+	if !strings.Contains(files["xl/workbook.xml"], `name="Results &amp; more"`) {
+		t.Errorf("Want an escaped sheet name, got %s", files["xl/workbook.xml"])
+	}
+	return files["xl/worksheets/sheet1.xml"]
+}
+
+func wellFormed(t *testing.T, name, body string) {
+	t.Helper()
+	d := xml.NewDecoder(strings.NewReader(body))
+	for {
+		_, err := d.Token()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatalf("%s is not well-formed: %v", name, err)
+		}
+	}
+}
+
+func TestWriteRow(t *testing.T) {
+	date := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	tt := []struct {
+		name string
+		row  []interface{}
+		want string
+	}{
+		{"Empty", []interface{}{nil}, "<row><c/></row>"},
+		{"Integers", []interface{}{7, int64(-3)}, "<row><c><v>7</v></c><c><v>-3</v></c></row>"},
+		{"Float", []interface{}{62.5}, "<row><c><v>62.5</v></c></row>"},
+		{"Booleans", []interface{}{true, false}, `<row><c t="b"><v>1</v></c><c t="b"><v>0</v></c></row>`},
+		{"Time", []interface{}{date}, `<row><c t="inlineStr"><is><t xml:space="preserve">2020-01-02T03:04:05Z</t></is></c></row>`},
+		{"Escaped text", []interface{}{"<b> & =1+1"}, `<row><c t="inlineStr"><is><t xml:space="preserve">&lt;b&gt; &amp; =1+1</t></is></c></row>`},
+		{"NaN and infinity", []interface{}{math.NaN(), math.Inf(1), math.Inf(-1)}, "<row><c/><c/><c/></row>"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if s := sheet(t, tc.row); !strings.Contains(s, "<sheetData>"+tc.want+"</sheetData>") {
+				t.Errorf("Want %s, got %s", tc.want, s)
+			}
+		})
+	}
+}
+
+func TestManyRows(t *testing.T) {
+	var rows [][]interface{}
+	for i := 0; i < 1000; i++ {
+		rows = append(rows, []interface{}{i, "name", float64(i) / 3})
+	}
+	if n := strings.Count(sheet(t, rows...), "<row>"); n != 1000 {
+		t.Errorf("Want 1000 rows, got %d", n)
+	}
+}